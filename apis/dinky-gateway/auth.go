@@ -0,0 +1,55 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// keyring authenticates clients by the key they present in X-API-Key or as
+// an Authorization bearer token. Keys can be swapped at runtime for
+// rotation. Keys are never logged; only client names are.
+type keyring struct {
+	mu   sync.RWMutex
+	keys map[string]string // client name -> key
+}
+
+func newKeyring(keys map[string]string) *keyring {
+	return &keyring{keys: keys}
+}
+
+// client returns the name of the client owning key. Every key is compared in
+// constant time so response timing does not reveal which keys exist.
+func (k *keyring) client(key string) (string, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	var match string
+	for name, want := range k.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(want)) == 1 {
+			match = name
+		}
+	}
+	return match, match != ""
+}
+
+func (k *keyring) replace(keys map[string]string) {
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+}
+
+// presentedKey extracts the caller's key and removes it from the request so
+// it is never forwarded upstream. A non-bearer Authorization header is left
+// alone for the upstream to use.
+func presentedKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		r.Header.Del("X-API-Key")
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		r.Header.Del("Authorization")
+		return token
+	}
+	return ""
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"dinky-shared/config"
+	"dinky-shared/logging"
+)
+
+// Config holds the runtime settings of the gateway, layered by config.Load.
+type Config struct {
+	Port     int     `json:"port"`
+	LogLevel string  `json:"log_level"`
+	Routes   []Route `json:"routes"`
+	// APIKeys maps a client name to its key. The name shows up in logs,
+	// metrics and the X-Gateway-Client header sent upstream.
+	APIKeys map[string]string `json:"api_keys"`
+	// APIKeysFile holds the keys instead, one client:key per line, so they
+	// can come from a Docker secret rather than the environment. The file is
+	// re-read every KeyReloadInterval and on SIGHUP to rotate keys.
+	APIKeysFile       string          `json:"api_keys_file"`
+	KeyReloadInterval config.Duration `json:"key_reload_interval"`
+	UpstreamTimeout   config.Duration `json:"upstream_timeout"`
+	ScrapeTimeout     config.Duration `json:"scrape_timeout"`
+	ShutdownTimeout   config.Duration `json:"shutdown_timeout"`
+}
+
+// Route sends every request under Prefix to Upstream with the prefix
+// removed, so /example/api/v1/users reaches example-api as /api/v1/users.
+type Route struct {
+	Name     string `json:"name"`
+	Prefix   string `json:"prefix"`
+	Upstream string `json:"upstream"`
+	// MetricsPath is scraped on the upstream and merged into the gateway's
+	// /metrics. Empty leaves the upstream out.
+	MetricsPath string `json:"metrics_path"`
+	// HealthPath is probed by the gateway's /readyz. Critical makes a failing
+	// upstream mark the whole gateway not ready instead of degraded.
+	HealthPath string `json:"health_path"`
+	Critical   bool   `json:"critical"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Port:     8080,
+		LogLevel: "info",
+		Routes: []Route{
+			{Name: "example-api", Prefix: "/example", Upstream: "http://example-api:8080", MetricsPath: "/metrics", HealthPath: "/readyz"},
+		},
+		KeyReloadInterval: config.Duration{Duration: 30 * time.Second},
+		UpstreamTimeout:   config.Duration{Duration: 30 * time.Second},
+		ScrapeTimeout:     config.Duration{Duration: 5 * time.Second},
+		ShutdownTimeout:   config.Duration{Duration: 20 * time.Second},
+	}
+}
+
+// loadConfig builds the configuration and fails on any invalid value rather
+// than silently falling back to a default.
+func loadConfig() (Config, error) {
+	cfg := defaultConfig()
+	if err := config.Load(&cfg, cfg.env()); err != nil {
+		return cfg, err
+	}
+	if cfg.APIKeysFile != "" {
+		if os.Getenv("API_KEYS") != "" {
+			return cfg, fmt.Errorf("set only one of API_KEYS and API_KEYS_FILE")
+		}
+		keys, err := readAPIKeysFile(cfg.APIKeysFile)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.APIKeys = keys
+	}
+	return cfg, cfg.validate()
+}
+
+// env names the environment variable behind each setting.
+func (c *Config) env() config.Env {
+	return config.Env{
+		"PORT":      config.IntVar(&c.Port),
+		"LOG_LEVEL": config.StringVar(&c.LogLevel),
+		// API_KEYS is a comma-separated list of client:key pairs and
+		// replaces any keys from the config file.
+		"API_KEYS": func(v string) error {
+			keys, err := parseAPIKeys(v)
+			if err != nil {
+				return err
+			}
+			c.APIKeys = keys
+			return nil
+		},
+		"API_KEYS_FILE":       config.StringVar(&c.APIKeysFile),
+		"KEY_RELOAD_INTERVAL": config.DurationVar(&c.KeyReloadInterval),
+		"UPSTREAM_TIMEOUT":    config.DurationVar(&c.UpstreamTimeout),
+		"SCRAPE_TIMEOUT":      config.DurationVar(&c.ScrapeTimeout),
+		"SHUTDOWN_TIMEOUT":    config.DurationVar(&c.ShutdownTimeout),
+	}
+}
+
+// parseAPIKeys reads comma-separated client:key pairs. Errors name the entry
+// by position only, so a malformed key never ends up in the logs.
+func parseAPIKeys(v string) (map[string]string, error) {
+	keys := map[string]string{}
+	for i, pair := range strings.Split(v, ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || name == "" || key == "" {
+			return nil, fmt.Errorf("entry %d must look like client:key", i+1)
+		}
+		keys[name] = key
+	}
+	return keys, nil
+}
+
+func (c Config) validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("port %d is out of range", c.Port)
+	}
+	if !logging.Valid(c.LogLevel) {
+		return fmt.Errorf("log_level %q must be one of debug, info, warn, error", c.LogLevel)
+	}
+	if err := validateAPIKeys(c.APIKeys); err != nil {
+		return err
+	}
+	if len(c.Routes) == 0 {
+		return fmt.Errorf("at least one route is required")
+	}
+	seen := map[string]bool{}
+	names := map[string]bool{}
+	for _, r := range c.Routes {
+		if r.Name == "" {
+			return fmt.Errorf("route for prefix %q has no name", r.Prefix)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("route name %q is used twice", r.Name)
+		}
+		names[r.Name] = true
+		if !strings.HasPrefix(r.Prefix, "/") || strings.HasSuffix(r.Prefix, "/") {
+			return fmt.Errorf("route %s: prefix %q must start with / and not end with one", r.Name, r.Prefix)
+		}
+		if reservedPaths[r.Prefix] {
+			return fmt.Errorf("route %s: prefix %q is used by the gateway itself", r.Name, r.Prefix)
+		}
+		if seen[r.Prefix] {
+			return fmt.Errorf("route %s: prefix %q is already routed", r.Name, r.Prefix)
+		}
+		seen[r.Prefix] = true
+		u, err := url.Parse(r.Upstream)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("route %s: upstream %q must be an http(s) URL", r.Name, r.Upstream)
+		}
+		if r.MetricsPath != "" && !strings.HasPrefix(r.MetricsPath, "/") {
+			return fmt.Errorf("route %s: metrics_path %q must start with /", r.Name, r.MetricsPath)
+		}
+		if r.HealthPath != "" && !strings.HasPrefix(r.HealthPath, "/") {
+			return fmt.Errorf("route %s: health_path %q must start with /", r.Name, r.HealthPath)
+		}
+	}
+	for name, d := range map[string]config.Duration{
+		"key_reload_interval": c.KeyReloadInterval,
+		"upstream_timeout":    c.UpstreamTimeout,
+		"scrape_timeout":      c.ScrapeTimeout,
+		"shutdown_timeout":    c.ShutdownTimeout,
+	} {
+		if d.Duration <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+	return nil
+}
+
+// validateAPIKeys also runs on every key-file reload. A key shared by two
+// clients would make the request's client name depend on map order, so it
+// is rejected; errors name clients only, never keys.
+func validateAPIKeys(keys map[string]string) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("at least one API key is required (set API_KEYS or API_KEYS_FILE)")
+	}
+	owners := map[string]string{}
+	for _, name := range clientNames(keys) {
+		key := keys[name]
+		if len(key) < 16 {
+			return fmt.Errorf("API key for %q must be at least 16 characters", name)
+		}
+		if other, ok := owners[key]; ok {
+			return fmt.Errorf("clients %q and %q have the same API key", other, name)
+		}
+		owners[key] = name
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{name: "shipped gateway.json", file: ""},
+		{name: "misspelled key", file: `{"upstream_timout": "5s"}`, wantErr: `unknown field "upstream_timout"`},
+		{name: "misspelled route key", file: `{"routes": [{"name": "a", "prefix": "/a", "upstrem": "http://a:8080"}]}`, wantErr: `unknown field "upstrem"`},
+		{name: "invalid route", file: `{"routes": [{"name": "a", "prefix": "a", "upstream": "http://a:8080"}]}`, wantErr: "prefix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "gateway.json"
+			if tt.file != "" {
+				path = filepath.Join(t.TempDir(), "gateway.json")
+				if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Setenv("CONFIG_FILE", path)
+			t.Setenv("API_KEYS", "tests:0123456789abcdef")
+
+			cfg, err := loadConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cfg.Routes) != 1 || cfg.Routes[0].Name != "example-api" {
+				t.Errorf("routes %+v", cfg.Routes)
+			}
+		})
+	}
+}
+
+func TestValidateAPIKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys map[string]string
+		ok   bool
+	}{
+		{name: "none", keys: map[string]string{}},
+		{name: "nil", keys: nil},
+		{name: "long enough", keys: map[string]string{"admin": "0123456789abcdef"}, ok: true},
+		{name: "too short", keys: map[string]string{"admin": "0123456789abcde"}},
+		{name: "one short among many", keys: map[string]string{"admin": "0123456789abcdef", "ci": "short"}},
+		{name: "distinct keys", keys: map[string]string{"admin": "0123456789abcdef", "ci": "fedcba9876543210"}, ok: true},
+		{name: "key shared by two clients", keys: map[string]string{"admin": "0123456789abcdef", "ci": "0123456789abcdef"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAPIKeys(tt.keys)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err %v, want ok=%v", err, tt.ok)
+			}
+			for _, key := range tt.keys {
+				if err != nil && strings.Contains(err.Error(), key) {
+					t.Errorf("err %v must not echo a key", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+module dinky-gateway
+
+go 1.21
+
+require (
+	dinky-shared v0.0.0
+	github.com/prometheus/client_golang v1.19.1
+	github.com/prometheus/client_model v0.5.0
+	github.com/prometheus/common v0.48.0
+	google.golang.org/protobuf v1.33.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+)
+
+// Code shared with example-api; Docker builds use apis/ as the context.
+replace dinky-shared => ../shared
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"dinky-shared/health"
+)
+
+// newReadinessChecks registers one check per route with a health_path. A
+// route marked critical makes the gateway not ready when its upstream is
+// down; other routes only degrade it.
+func newReadinessChecks(routes []Route, timeout time.Duration) *health.Checks {
+	h := health.New("dinky-gateway", labelled)
+	client := &http.Client{Timeout: timeout}
+	for _, r := range routes {
+		if r.HealthPath == "" {
+			continue
+		}
+		url := r.Upstream + r.HealthPath
+		h.Register(health.Check{Name: r.Name, Critical: r.Critical, Timeout: timeout, Run: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return fmt.Errorf("%s returned %s", url, resp.Status)
+			}
+			return nil
+		}})
+	}
+	return h
+}
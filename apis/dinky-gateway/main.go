@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"dinky-shared/health"
+	"dinky-shared/logging"
+)
+
+func main() {
+	fmt.Println("🚀 Starting Dinky Gateway")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("❌ Invalid configuration: %v", err)
+	}
+	logging.SetLevel(cfg.LogLevel)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler(cfg.Routes, cfg.ScrapeTimeout.Duration))
+	mux.HandleFunc("/healthz", health.Healthz("dinky-gateway"))
+	mux.HandleFunc("/readyz", newReadinessChecks(cfg.Routes, cfg.ScrapeTimeout.Duration).Readyz)
+	keys := newKeyring(cfg.APIKeys)
+	mux.Handle("/", newGateway(cfg, keys))
+
+	for _, r := range cfg.Routes {
+		fmt.Printf("🔀 %s -> %s (%s)\n", r.Prefix, r.Upstream, r.Name)
+	}
+
+	// No WriteTimeout: proxied WebSocket and SSE streams stay open for as
+	// long as the upstream keeps them; upstream_timeout bounds the wait for
+	// response headers instead.
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.APIKeysFile != "" {
+		go watchKeyFile(ctx, cfg.APIKeysFile, cfg.KeyReloadInterval.Duration, keys)
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		fmt.Printf("🌐 Dinky Gateway listening on http://localhost:%d\n", cfg.Port)
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("❌ Server error: %v", err)
+		}
+	case <-ctx.Done():
+		fmt.Println("🛑 Shutting down, draining connections...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logging.Warnf("graceful shutdown did not complete: %v", err)
+		}
+	}
+	fmt.Println("👋 Dinky Gateway stopped")
+}
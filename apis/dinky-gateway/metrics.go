@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"dinky-shared/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// registry holds the gateway's own metrics. Everything registered through
+// factory carries service="dinky-gateway", matching the label added to
+// scraped upstream metrics so /metrics has one consistent label set.
+var (
+	registry = prometheus.NewRegistry()
+	labelled = prometheus.WrapRegistererWith(prometheus.Labels{"service": "dinky-gateway"}, registry)
+	factory  = promauto.With(labelled)
+)
+
+var (
+	gatewayRequestsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_requests_total",
+		Help: "Requests handled by the gateway, by route, client, method and status code.",
+	}, []string{"route", "client", "method", "status"})
+
+	gatewayRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_request_duration_seconds",
+		Help:    "Gateway request latency in seconds including the upstream call, by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route"})
+
+	gatewayAuthFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_auth_failures_total",
+		Help: "Requests rejected by API-key auth, by reason (missing/invalid).",
+	}, []string{"reason"})
+
+	gatewayUpstreamErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_upstream_errors_total",
+		Help: "Upstream calls that failed without a response, by route.",
+	}, []string{"route"})
+
+	gatewayScrapeSuccess = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_upstream_scrape_success",
+		Help: "Whether the last scrape of an upstream's metrics succeeded (1) or not (0), by route.",
+	}, []string{"route"})
+)
+
+func init() {
+	labelled.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// metricsHandler serves the gateway's metrics merged with those of every
+// route that has a metrics_path. An unreachable upstream is logged and left
+// out instead of failing the whole scrape.
+func metricsHandler(routes []Route, timeout time.Duration) http.Handler {
+	gatherers := prometheus.Gatherers{registry}
+	client := &http.Client{Timeout: timeout}
+	for _, r := range routes {
+		if r.MetricsPath != "" {
+			gatherers = append(gatherers, upstreamGatherer{route: r.Name, url: r.Upstream + r.MetricsPath, client: client})
+		}
+	}
+	return promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+		ErrorLog:      promLogger{},
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// upstreamGatherer scrapes one upstream's text exposition and labels every
+// series with service=<route name>.
+type upstreamGatherer struct {
+	route  string
+	url    string
+	client *http.Client
+}
+
+func (g upstreamGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.scrape()
+	if err != nil {
+		gatewayScrapeSuccess.WithLabelValues(g.route).Set(0)
+		return nil, fmt.Errorf("scrape %s: %w", g.route, err)
+	}
+	gatewayScrapeSuccess.WithLabelValues(g.route).Set(1)
+
+	out := make([]*dto.MetricFamily, 0, len(families))
+	for _, mf := range families {
+		for _, m := range mf.Metric {
+			if !hasLabel(m, "service") {
+				m.Label = append(m.Label, &dto.LabelPair{Name: proto.String("service"), Value: proto.String(g.route)})
+			}
+		}
+		out = append(out, mf)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].GetName() < out[j].GetName() })
+	return out, nil
+}
+
+func (g upstreamGatherer) scrape() (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequest(http.MethodGet, g.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Ask for the classic text format, which TextParser understands.
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(resp.Body)
+}
+
+func hasLabel(m *dto.Metric, name string) bool {
+	for _, l := range m.Label {
+		if l.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// promLogger routes promhttp errors through the leveled logger.
+type promLogger struct{}
+
+func (promLogger) Println(v ...interface{}) { logging.Warnf("metrics: %s", fmt.Sprint(v...)) }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dinky-shared/httpkit"
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+)
+
+// reservedPaths are served by the gateway itself and cannot be routed.
+var reservedPaths = map[string]bool{"/metrics": true, "/healthz": true, "/readyz": true}
+
+type upstream struct {
+	route Route
+	proxy *httputil.ReverseProxy
+}
+
+// gateway authenticates requests and forwards them to the upstream with the
+// longest matching prefix.
+type gateway struct {
+	keys      *keyring
+	upstreams []upstream // longest prefix first
+}
+
+func newGateway(cfg Config, keys *keyring) *gateway {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = cfg.UpstreamTimeout.Duration
+
+	g := &gateway{keys: keys}
+	for _, r := range cfg.Routes {
+		g.upstreams = append(g.upstreams, upstream{route: r, proxy: newProxy(r, transport)})
+	}
+	sort.Slice(g.upstreams, func(i, j int) bool {
+		return len(g.upstreams[i].route.Prefix) > len(g.upstreams[j].route.Prefix)
+	})
+	return g
+}
+
+func newProxy(r Route, transport http.RoundTripper) *httputil.ReverseProxy {
+	target, _ := url.Parse(r.Upstream) // checked by Config.validate
+	return &httputil.ReverseProxy{
+		Transport: transport,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Path = strings.TrimPrefix(pr.Out.URL.Path, r.Prefix)
+			pr.Out.URL.RawPath = strings.TrimPrefix(pr.Out.URL.RawPath, r.Prefix)
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			pr.Out.Header.Set("X-Forwarded-Prefix", r.Prefix)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			for _, h := range []string{"Location", "Content-Location"} {
+				if v := resp.Header.Get(h); v != "" {
+					resp.Header.Set(h, prefixLocation(v, r.Prefix, target))
+				}
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			gatewayUpstreamErrors.WithLabelValues(r.Name).Inc()
+			logging.Warnf("upstream %s failed for %s %s: %v", r.Name, req.Method, req.URL.Path, err)
+			if errors.Is(err, context.DeadlineExceeded) {
+				respond.Error(w, http.StatusGatewayTimeout, codeTimeout, r.Name+" did not respond in time")
+				return
+			}
+			respond.Error(w, http.StatusBadGateway, codeBadGateway, r.Name+" is unavailable")
+		},
+	}
+}
+
+// prefixLocation maps a URL the upstream put in a Location header back into
+// the gateway's address space: /api/v1/users/4 becomes
+// /example/api/v1/users/4, as does an absolute URL on the upstream's own
+// host. Relative references and other hosts are left alone; clients already
+// resolve the former against the prefixed request URL.
+func prefixLocation(loc, prefix string, target *url.URL) string {
+	u, err := url.Parse(loc)
+	if err != nil {
+		return loc
+	}
+	switch {
+	case u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/"):
+	case u.Scheme == target.Scheme && u.Host == target.Host:
+		u.Scheme, u.Host, u.User = "", "", nil
+	default:
+		return loc
+	}
+	u.Path = prefix + u.Path
+	if u.RawPath != "" {
+		u.RawPath = prefix + u.RawPath
+	}
+	return u.String()
+}
+
+func (g *gateway) match(path string) (upstream, bool) {
+	for _, u := range g.upstreams {
+		p := u.route.Prefix
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return u, true
+		}
+	}
+	return upstream{}, false
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := httpkit.NewStatusRecorder(w)
+	route, client := "unmatched", "anonymous"
+	defer func() {
+		took := time.Since(start)
+		gatewayRequestsTotal.WithLabelValues(route, client, r.Method, strconv.Itoa(rec.Status)).Inc()
+		gatewayRequestDuration.WithLabelValues(route).Observe(took.Seconds())
+		logging.Infof("%s %s route=%s client=%s status=%d duration=%s", r.Method, r.URL.Path, route, client, rec.Status, took)
+	}()
+
+	key := presentedKey(r)
+	if key == "" {
+		gatewayAuthFailures.WithLabelValues("missing").Inc()
+		rec.Header().Set("WWW-Authenticate", `Bearer realm="dinky-gateway"`)
+		respond.Error(rec, http.StatusUnauthorized, codeUnauthorized, "an API key is required (X-API-Key header or Bearer token)")
+		return
+	}
+	name, ok := g.keys.client(key)
+	if !ok {
+		gatewayAuthFailures.WithLabelValues("invalid").Inc()
+		rec.Header().Set("WWW-Authenticate", `Bearer realm="dinky-gateway", error="invalid_token"`)
+		respond.Error(rec, http.StatusUnauthorized, codeUnauthorized, "the API key is not valid")
+		return
+	}
+	client = name
+
+	u, ok := g.match(r.URL.Path)
+	if !ok {
+		respond.Error(rec, http.StatusNotFound, codeNotFound, "no service is routed at "+r.URL.Path)
+		return
+	}
+	route = u.route.Name
+	r.Header.Set("X-Gateway-Client", client)
+	u.proxy.ServeHTTP(rec, r)
+}
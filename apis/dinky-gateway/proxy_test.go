@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPrefixLocation(t *testing.T) {
+	target, _ := url.Parse("http://example-api:8080")
+	tests := []struct {
+		loc, want string
+	}{
+		{"/api/v1/users/4", "/example/api/v1/users/4"},
+		{"/api/v1/users?page=2", "/example/api/v1/users?page=2"},
+		{"/files/a%2Fb", "/example/files/a%2Fb"},
+		{"http://example-api:8080/api/v1/users/4", "/example/api/v1/users/4"},
+		{"https://elsewhere.example.com/x", "https://elsewhere.example.com/x"},
+		{"4", "4"},
+		{"../users", "../users"},
+	}
+	for _, tt := range tests {
+		if got := prefixLocation(tt.loc, "/example", target); got != tt.want {
+			t.Errorf("prefixLocation(%q) = %q, want %q", tt.loc, got, tt.want)
+		}
+	}
+}
+
+func TestProxyRewritesLocation(t *testing.T) {
+	var gotPath, gotPrefix, gotKey string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotPrefix, gotKey = r.URL.Path, r.Header.Get("X-Forwarded-Prefix"), r.Header.Get("X-API-Key")
+		w.Header().Set("Location", "/api/v1/users/4")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer upstream.Close()
+
+	cfg := defaultConfig()
+	cfg.Routes = []Route{{Name: "example-api", Prefix: "/example", Upstream: upstream.URL}}
+	const key = "0123456789abcdef-test"
+	g := newGateway(cfg, newKeyring(map[string]string{"tests": key}))
+
+	req := httptest.NewRequest(http.MethodPost, "/example/api/v1/users", strings.NewReader(`{}`))
+	req.Header.Set("X-API-Key", key)
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+	if got, want := rec.Header().Get("Location"), "/example/api/v1/users/4"; got != want {
+		t.Errorf("Location %q, want %q", got, want)
+	}
+	if gotPath != "/api/v1/users" || gotPrefix != "/example" {
+		t.Errorf("upstream saw path %q prefix %q, want /api/v1/users and /example", gotPath, gotPrefix)
+	}
+	if gotKey != "" {
+		t.Errorf("API key was forwarded upstream")
+	}
+}
@@ -0,0 +1,11 @@
+package main
+
+// Error codes returned in the "code" field of the respond.Envelope error body,
+// the same shape example-api uses, so clients see one error format behind the
+// gateway.
+const (
+	codeUnauthorized = "unauthorized"
+	codeNotFound     = "not_found"
+	codeBadGateway   = "bad_gateway"
+	codeTimeout      = "gateway_timeout"
+)
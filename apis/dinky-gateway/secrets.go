@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"dinky-shared/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var gatewayKeyReloads = factory.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_api_key_reloads_total",
+	Help: "Reloads of the API key file after it changed, by result (ok/error).",
+}, []string{"result"})
+
+// readAPIKeysFile parses a key file: one client:key per line (commas also
+// separate entries), blank lines and # comments ignored. This is the format
+// to put in a Docker secret.
+func readAPIKeysFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read API key file: %w", err)
+	}
+	return parseAPIKeysFile(data)
+}
+
+func parseAPIKeysFile(data []byte) (map[string]string, error) {
+	var entries []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	keys, err := parseAPIKeys(strings.Join(entries, ","))
+	if err != nil {
+		return nil, fmt.Errorf("API key file: %w", err)
+	}
+	return keys, nil
+}
+
+// watchKeyFile re-reads path every interval and on SIGHUP, swapping the
+// keys into ring when the content changed and still validates. A bad file
+// keeps the previous keys, so a half-written rotation cannot lock every
+// client out.
+func watchKeyFile(ctx context.Context, path string, interval time.Duration, ring *keyring) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	last, _ := os.ReadFile(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-hup:
+			logging.Infof("SIGHUP received, reloading API keys")
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			gatewayKeyReloads.WithLabelValues("error").Inc()
+			logging.Warnf("keeping current API keys: %v", err)
+			continue
+		}
+		if bytes.Equal(data, last) {
+			continue
+		}
+		last = data
+		keys, err := parseAPIKeysFile(data)
+		if err == nil {
+			err = validateAPIKeys(keys)
+		}
+		if err != nil {
+			gatewayKeyReloads.WithLabelValues("error").Inc()
+			logging.Warnf("keeping current API keys: %v", err)
+			continue
+		}
+		ring.replace(keys)
+		gatewayKeyReloads.WithLabelValues("ok").Inc()
+		logging.Infof("reloaded %d API keys from %s (clients: %s)", len(keys), path, strings.Join(clientNames(keys), ", "))
+	}
+}
+
+func clientNames(keys map[string]string) []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAPIKeysFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "one per line",
+			data: "admin:0123456789abcdef\nci:fedcba9876543210\n",
+			want: map[string]string{"admin": "0123456789abcdef", "ci": "fedcba9876543210"},
+		},
+		{
+			name: "comments, blank lines and padding",
+			data: "# rotated 2024-05-01\n\n  admin:0123456789abcdef  \r\n",
+			want: map[string]string{"admin": "0123456789abcdef"},
+		},
+		{
+			name: "colon inside the key",
+			data: "admin:abc:def",
+			want: map[string]string{"admin": "abc:def"},
+		},
+		{name: "only comments", data: "# nothing yet\n", wantErr: "entry 1"},
+		{name: "missing key", data: "admin:0123456789abcdef\nci:\n", wantErr: "entry 2"},
+		{name: "missing name", data: ":0123456789abcdef", wantErr: "entry 1"},
+		{name: "no separator", data: "0123456789abcdef", wantErr: "entry 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAPIKeysFile([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAPIKeysFileHidesKeys(t *testing.T) {
+	_, err := parseAPIKeysFile([]byte("admin 0123456789abcdef"))
+	if err == nil || strings.Contains(err.Error(), "0123456789abcdef") {
+		t.Errorf("err %v must not echo the key", err)
+	}
+}
+
+func TestWatchKeyFileRotation(t *testing.T) {
+	const oldKey, newKey = "0123456789abcdef", "fedcba9876543210"
+	path := filepath.Join(t.TempDir(), "api_keys")
+	ring := newKeyring(map[string]string{"admin": oldKey})
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor := func(key string, want bool) {
+		t.Helper()
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if _, ok := ring.client(key); ok == want {
+				return
+			}
+		}
+		t.Fatalf("key %s accepted = %v after 2s, want %v", key, !want, want)
+	}
+
+	write("admin:" + oldKey)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchKeyFile(ctx, path, 10*time.Millisecond, ring)
+	// Give the watcher time to read the starting file, which it compares
+	// every later read against.
+	time.Sleep(50 * time.Millisecond)
+
+	write("admin:" + oldKey + "\nnext:" + newKey)
+	waitFor(newKey, true)
+
+	write("next:tooshort")
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := ring.client(newKey); !ok {
+		t.Fatal("an invalid file replaced the keys")
+	}
+
+	write("admin:" + newKey + "\nnext:" + newKey)
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := ring.client(oldKey); !ok {
+		t.Fatal("a file with a duplicated key replaced the keys")
+	}
+
+	write("next:" + newKey)
+	waitFor(oldKey, false)
+}
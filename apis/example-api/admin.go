@@ -0,0 +1,30 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"dinky-shared/respond"
+)
+
+// adminAuth guards the /admin endpoints, which can rewrite, wipe or restore
+// the database. With no token configured they are switched off; otherwise
+// callers must present the token as an Authorization bearer token.
+func adminAuth(token string) func(http.HandlerFunc) http.Handler {
+	return func(next http.HandlerFunc) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				respond.Error(w, http.StatusForbidden, codeForbidden, "admin endpoints are disabled; set ADMIN_TOKEN to enable them")
+				return
+			}
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="example-api admin"`)
+				respond.Error(w, http.StatusUnauthorized, codeUnauthorized, "a valid admin token is required (Authorization: Bearer)")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
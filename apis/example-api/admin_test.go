@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testAdminToken = "0123456789abcdef-admin"
+
+// newAdminTestServer is newTestServer with the admin endpoints enabled
+// under testAdminToken.
+func newAdminTestServer(t *testing.T) (http.Handler, *Store, *backupService) {
+	t.Helper()
+	return newTestServerWith(t, func(cfg *Config) { cfg.AdminToken = testAdminToken })
+}
+
+// serveAdmin is serve with testAdminToken presented as a bearer token.
+func serveAdmin(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	r.Header.Set("Authorization", "Bearer "+testAdminToken)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestAdminAuth(t *testing.T) {
+	disabled, _, _ := newTestServer(t)
+	enabled, _, _ := newAdminTestServer(t)
+
+	tests := []struct {
+		name          string
+		router        http.Handler
+		authorization string
+		wantStatus    int
+		wantCode      string
+	}{
+		{"disabled without a token", disabled, "", http.StatusForbidden, codeForbidden},
+		{"disabled ignores any token", disabled, "Bearer " + testAdminToken, http.StatusForbidden, codeForbidden},
+		{"missing token", enabled, "", http.StatusUnauthorized, codeUnauthorized},
+		{"wrong token", enabled, "Bearer 0123456789abcdef-wrong", http.StatusUnauthorized, codeUnauthorized},
+		{"not a bearer token", enabled, testAdminToken, http.StatusUnauthorized, codeUnauthorized},
+		{"right token", enabled, "Bearer " + testAdminToken, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/admin/faults", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			tt.router.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := errorCode(rec); got != tt.wantCode {
+				t.Errorf("code = %q, want %q", got, tt.wantCode)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestAdminRoutesRequireToken(t *testing.T) {
+	router, _, _ := newAdminTestServer(t)
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/admin/faults"},
+		{http.MethodPut, "/admin/faults"},
+		{http.MethodPost, "/admin/seed"},
+		{http.MethodPost, "/admin/reset"},
+		{http.MethodGet, "/admin/backups"},
+		{http.MethodPost, "/admin/backups"},
+		{http.MethodPost, "/admin/backups/example-api-20260101T000000Z.db/restore"},
+	} {
+		if rec := serve(router, route.method, route.path, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without a token = %d, want 401", route.method, route.path, rec.Code)
+		}
+	}
+}
+
+func TestValidateAdminToken(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.AdminToken = "short"
+	if err := cfg.validate(); err == nil {
+		t.Error("validate accepted a 5-character admin token")
+	}
+	cfg.AdminToken = testAdminToken
+	if err := cfg.validate(); err != nil {
+		t.Errorf("validate: %v", err)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	backupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "backups_total",
+		Help: "Database backups attempted, by trigger (manual/scheduled) and result (ok/error).",
+	}, []string{"trigger", "result"})
+
+	backupLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "backup_last_success_timestamp_seconds",
+		Help: "Unix time of the newest successful backup.",
+	})
+
+	backupInterval = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "backup_interval_seconds",
+		Help: "Configured interval between scheduled backups; 0 when scheduling is off.",
+	})
+
+	restoresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "restores_total",
+		Help: "Database restores attempted, by result (ok/error/checksum_mismatch).",
+	}, []string{"result"})
+)
+
+// backupNamePattern matches the names newBackupName generates, so path input
+// never reaches the filesystem unchecked. Names from before millisecond
+// resolution, without the fraction, still match.
+var backupNamePattern = regexp.MustCompile(`^example-api-\d{8}T\d{6}(\.\d{3})?Z\.db$`)
+
+var errChecksumMismatch = errors.New("backup checksum does not match")
+
+// Backup describes one snapshot in the backup directory. Each snapshot
+// <name> has a <name>.sha256 file next to it in sha256sum format.
+type Backup struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// backupService writes database snapshots to dir, keeping the newest keep.
+// Uploaded file contents are not included; only their metadata is.
+type backupService struct {
+	store    *Store
+	dir      string
+	keep     int
+	interval time.Duration
+	mu       sync.Mutex // serialises backups, restores and pruning
+}
+
+func newBackupService(store *Store, cfg Config) (*backupService, error) {
+	if err := os.MkdirAll(cfg.BackupDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create backup dir: %w", err)
+	}
+	bs := &backupService{store: store, dir: cfg.BackupDir, keep: cfg.BackupKeep, interval: cfg.BackupInterval.Duration}
+	backupInterval.Set(bs.interval.Seconds())
+	if backups, err := bs.list(); err == nil && len(backups) > 0 {
+		backupLastSuccess.Set(float64(backups[0].CreatedAt.Unix()))
+	}
+	return bs, nil
+}
+
+// backupTimeLayout also parses the older names without milliseconds, since
+// time.Parse accepts a fraction after the seconds the layout does not show.
+const backupTimeLayout = "20060102T150405Z"
+
+func newBackupName(t time.Time) string {
+	return "example-api-" + t.UTC().Format("20060102T150405.000Z") + ".db"
+}
+
+// create snapshots the database, writes its checksum and prunes old
+// snapshots. The snapshot is written under a temporary name and renamed, so
+// a crash never leaves a partial file that looks complete.
+func (bs *backupService) create(ctx context.Context, trigger string) (Backup, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	b, err := bs.snapshot(ctx)
+	if err != nil {
+		backupsTotal.WithLabelValues(trigger, "error").Inc()
+		return Backup{}, err
+	}
+	backupsTotal.WithLabelValues(trigger, "ok").Inc()
+	backupLastSuccess.Set(float64(b.CreatedAt.Unix()))
+	if err := bs.prune(); err != nil {
+		logging.Warnf("pruning backups: %v", err)
+	}
+	return b, nil
+}
+
+func (bs *backupService) snapshot(ctx context.Context) (Backup, error) {
+	// Callers hold bs.mu, so stepping past a name already taken within the
+	// same millisecond cannot race another backup.
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	name := newBackupName(now)
+	final := filepath.Join(bs.dir, name)
+	for {
+		if _, err := os.Stat(final); err != nil {
+			break
+		}
+		now = now.Add(time.Millisecond)
+		name = newBackupName(now)
+		final = filepath.Join(bs.dir, name)
+	}
+	tmp := final + ".tmp"
+	os.Remove(tmp)
+	if err := bs.store.Backup(ctx, tmp); err != nil {
+		os.Remove(tmp)
+		return Backup{}, fmt.Errorf("snapshot database: %w", err)
+	}
+
+	sum, size, err := fileSHA256(tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return Backup{}, err
+	}
+	if err := os.WriteFile(final+".sha256", []byte(sum+"  "+name+"\n"), 0o644); err != nil {
+		os.Remove(tmp)
+		return Backup{}, err
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		os.Remove(tmp)
+		os.Remove(final + ".sha256")
+		return Backup{}, err
+	}
+	return Backup{Name: name, Size: size, SHA256: sum, CreatedAt: now}, nil
+}
+
+// prune removes all but the newest keep snapshots.
+func (bs *backupService) prune() error {
+	backups, err := bs.list()
+	if err != nil {
+		return err
+	}
+	for i := bs.keep; i < len(backups); i++ {
+		path := filepath.Join(bs.dir, backups[i].Name)
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		os.Remove(path + ".sha256")
+		logging.Debugf("pruned backup %s", backups[i].Name)
+	}
+	return nil
+}
+
+// list returns the snapshots in dir, newest first.
+func (bs *backupService) list() ([]Backup, error) {
+	entries, err := os.ReadDir(bs.dir)
+	if err != nil {
+		return nil, err
+	}
+	backups := []Backup{}
+	for _, e := range entries {
+		if !backupNamePattern.MatchString(e.Name()) {
+			continue
+		}
+		b, err := bs.describe(e.Name())
+		if err != nil {
+			logging.Warnf("skipping backup %s: %v", e.Name(), err)
+			continue
+		}
+		backups = append(backups, b)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+func (bs *backupService) describe(name string) (Backup, error) {
+	path := filepath.Join(bs.dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return Backup{}, err
+	}
+	created, err := time.Parse(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, "example-api-"), ".db"))
+	if err != nil {
+		return Backup{}, err
+	}
+	recorded, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		return Backup{}, fmt.Errorf("missing checksum: %w", err)
+	}
+	sum, _, _ := strings.Cut(string(recorded), " ")
+	return Backup{Name: name, Size: info.Size(), SHA256: sum, CreatedAt: created}, nil
+}
+
+// restore verifies a snapshot against its recorded checksum and loads it
+// into the live database.
+func (bs *backupService) restore(ctx context.Context, name string) (Backup, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	b, err := bs.describe(name)
+	if err != nil {
+		restoresTotal.WithLabelValues("error").Inc()
+		return Backup{}, err
+	}
+	path := filepath.Join(bs.dir, name)
+	sum, _, err := fileSHA256(path)
+	if err != nil {
+		restoresTotal.WithLabelValues("error").Inc()
+		return Backup{}, err
+	}
+	if sum != b.SHA256 {
+		restoresTotal.WithLabelValues("checksum_mismatch").Inc()
+		return Backup{}, errChecksumMismatch
+	}
+	if err := bs.store.Restore(ctx, path); err != nil {
+		restoresTotal.WithLabelValues("error").Inc()
+		return Backup{}, err
+	}
+	restoresTotal.WithLabelValues("ok").Inc()
+	return b, nil
+}
+
+// schedule takes a backup every interval until stop is closed. If the
+// newest backup is already older than interval, one is taken right away.
+func (bs *backupService) schedule(stop <-chan struct{}) {
+	if bs.interval <= 0 {
+		return
+	}
+	run := func() {
+		b, err := bs.create(context.Background(), "scheduled")
+		if err != nil {
+			logging.Errorf("scheduled backup failed: %v", err)
+			return
+		}
+		logging.Infof("scheduled backup %s written (%d bytes)", b.Name, b.Size)
+	}
+	if backups, err := bs.list(); err == nil && (len(backups) == 0 || time.Since(backups[0].CreatedAt) > bs.interval) {
+		run()
+	}
+
+	ticker := time.NewTicker(bs.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
+
+func fileSHA256(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+func (bs *backupService) handleCreate(w http.ResponseWriter, r *http.Request) {
+	b, err := bs.create(r.Context(), "manual")
+	if err != nil {
+		logging.Errorf("backup failed: %v", err)
+		respond.Error(w, http.StatusInternalServerError, codeInternal, "backup failed")
+		return
+	}
+	logging.Infof("backup %s written (%d bytes)", b.Name, b.Size)
+	respond.JSON(w, http.StatusCreated, b)
+}
+
+func (bs *backupService) handleList(w http.ResponseWriter, r *http.Request) {
+	backups, err := bs.list()
+	if err != nil {
+		logging.Errorf("listing backups: %v", err)
+		respond.Error(w, http.StatusInternalServerError, codeInternal, "internal error")
+		return
+	}
+	respond.JSON(w, http.StatusOK, backups)
+}
+
+func (bs *backupService) handleRestore(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !backupNamePattern.MatchString(name) {
+		respond.Error(w, http.StatusBadRequest, codeBadRequest, "backup name must look like example-api-20060102T150405.000Z.db")
+		return
+	}
+	b, err := bs.restore(r.Context(), name)
+	switch {
+	case err == nil:
+		logging.Warnf("database restored from backup %s", b.Name)
+		respond.JSON(w, http.StatusOK, b)
+	case errors.Is(err, os.ErrNotExist):
+		respond.Error(w, http.StatusNotFound, codeNotFound, "backup not found")
+	case errors.Is(err, errChecksumMismatch):
+		respond.Error(w, http.StatusUnprocessableEntity, codeValidationFailed, "backup "+name+" does not match its recorded checksum")
+	default:
+		logging.Errorf("restore failed: %v", err)
+		respond.Error(w, http.StatusInternalServerError, codeInternal, "restore failed")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	router, store, backups := newAdminTestServer(t)
+
+	dana, err := store.CreateUser("Dana", "dana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreatePost(dana.ID, "Hello", "First post"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serveAdmin(router, http.MethodPost, "/admin/backups")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create backup: status %d (body %s)", rec.Code, rec.Body)
+	}
+	var b Backup
+	if err := json.Unmarshal(rec.Body.Bytes(), &b); err != nil {
+		t.Fatal(err)
+	}
+	if listed, err := backups.list(); err != nil || len(listed) != 1 || listed[0].SHA256 != b.SHA256 {
+		t.Fatalf("list = %+v, %v; want the new backup", listed, err)
+	}
+
+	rec = serveAdmin(router, http.MethodPost, "/admin/reset")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("reset: status %d", rec.Code)
+	}
+	if users, total, _ := store.ListUsers(UserQuery{Limit: 10}); total != 0 {
+		t.Fatalf("after reset: %d users left: %+v", total, users)
+	}
+
+	rec = serveAdmin(router, http.MethodPost, "/admin/backups/"+b.Name+"/restore")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restore: status %d (body %s)", rec.Code, rec.Body)
+	}
+
+	got, err := store.GetUser(dana.ID)
+	if err != nil {
+		t.Fatalf("user after restore: %v", err)
+	}
+	if got.Name != "Dana" || got.Email != "dana@example.com" {
+		t.Errorf("restored user %+v", got)
+	}
+	posts, total, err := store.ListPosts(PostQuery{UserID: dana.ID, Limit: 10})
+	if err != nil || total != 1 || posts[0].Title != "Hello" {
+		t.Errorf("restored posts %+v (total %d, err %v)", posts, total, err)
+	}
+	if _, err := store.CreateUser("Erin", "erin@example.com"); err != nil {
+		t.Errorf("store not writable after restore: %v", err)
+	}
+}
+
+func TestRestoreRejectsBadBackups(t *testing.T) {
+	router, _, backups := newAdminTestServer(t)
+
+	b, err := backups.create(context.Background(), "manual")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(filepath.Join(backups.dir, b.Name), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("corrupt"))
+	f.Close()
+
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{name: b.Name, status: http.StatusUnprocessableEntity},
+		{name: "example-api-20000101T000000.000Z.db", status: http.StatusNotFound},
+		{name: "example-api-20000101T000000Z.db", status: http.StatusNotFound},
+		{name: "example-api.db", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := serveAdmin(router, http.MethodPost, "/admin/backups/"+tt.name+"/restore")
+		if rec.Code != tt.status {
+			t.Errorf("restore %s: status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestBackupsInQuickSuccession(t *testing.T) {
+	router, _, backups := newAdminTestServer(t)
+
+	names := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		rec := serveAdmin(router, http.MethodPost, "/admin/backups")
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("backup %d: status %d (body %s)", i+1, rec.Code, rec.Body)
+		}
+		var b Backup
+		if err := json.Unmarshal(rec.Body.Bytes(), &b); err != nil {
+			t.Fatal(err)
+		}
+		names[b.Name] = true
+	}
+	if len(names) != 3 {
+		t.Errorf("three backups got %d distinct names: %v", len(names), names)
+	}
+	if listed, err := backups.list(); err != nil || len(listed) != 3 {
+		t.Errorf("list = %d backups, %v; want 3", len(listed), err)
+	}
+}
+
+func TestBackupListKeepsSecondResolutionNames(t *testing.T) {
+	_, _, backups := newAdminTestServer(t)
+
+	// A backup taken before names carried milliseconds.
+	const legacy = "example-api-20000101T000000Z.db"
+	path := filepath.Join(backups.dir, legacy)
+	if err := os.WriteFile(path, []byte("db"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".sha256", []byte("abc  "+legacy+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := backups.create(context.Background(), "manual")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := backups.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 || listed[0].Name != b.Name || listed[1].Name != legacy {
+		t.Errorf("list = %+v, want %s then %s", listed, b.Name, legacy)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var httpCacheResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_cache_responses_total",
+	Help: "Conditional-capable GET responses, by route and result (hit = 304 Not Modified, miss = full body).",
+}, []string{"route", "result"})
+
+// cacheControl is set once from the configuration at startup.
+var cacheControl = "no-cache"
+
+// setCacheMaxAge picks the Cache-Control value for cacheable responses. Zero
+// keeps "no-cache", so clients and proxies revalidate every time and get
+// 304s; a positive age lets them reuse responses without asking.
+func setCacheMaxAge(maxAge time.Duration) {
+	if maxAge > 0 {
+		cacheControl = fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+	}
+}
+
+// writeCachedJSON writes v as a 200 response with ETag, Last-Modified and
+// Cache-Control, or a bodiless 304 when the request's If-None-Match or
+// If-Modified-Since shows the client already has it. The ETag hashes the
+// encoded body, so it changes with any visible change including deletions.
+// lastModified is the item's updated_at; the zero time omits Last-Modified
+// and ignores If-Modified-Since.
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, v interface{}, lastModified time.Time) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		logging.Errorf("encode response: %v", err)
+		respond.Error(w, http.StatusInternalServerError, codeInternal, "internal error")
+		return
+	}
+	body = append(body, '\n')
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", cacheControl)
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	route := routeLabel(r)
+	if notModified(r, etag, lastModified) {
+		httpCacheResponses.WithLabelValues(route, "hit").Inc()
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	httpCacheResponses.WithLabelValues(route, "miss").Inc()
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// writeCachedList is writeCachedJSON for listings, which are validated by
+// ETag alone: deleting a row or pushing it off the page changes the list
+// without advancing any updated_at, so a Last-Modified taken from the rows
+// would let If-Modified-Since return a stale 304.
+func writeCachedList(w http.ResponseWriter, r *http.Request, v interface{}) {
+	writeCachedJSON(w, r, v, time.Time{})
+}
+
+// notModified applies RFC 9110 precedence: If-None-Match wins, and
+// If-Modified-Since is only consulted when it is absent.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !lastModified.Truncate(time.Second).After(t)
+	}
+	return false
+}
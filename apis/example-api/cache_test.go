@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	const etag = `"abc123"`
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 500_000_000, time.UTC)
+	at := func(t time.Time) string { return t.Format(http.TimeFormat) }
+
+	tests := []struct {
+		name         string
+		ifNoneMatch  string
+		ifModSince   string
+		lastModified time.Time
+		want         bool
+	}{
+		{name: "no conditions", lastModified: modified},
+		{name: "matching etag", ifNoneMatch: etag, want: true},
+		{name: "weak matching etag", ifNoneMatch: `W/"abc123"`, want: true},
+		{name: "etag in list", ifNoneMatch: `"other", "abc123"`, want: true},
+		{name: "wildcard", ifNoneMatch: "*", want: true},
+		{name: "stale etag", ifNoneMatch: `"other"`},
+		{name: "stale etag beats fresh date", ifNoneMatch: `"other"`, ifModSince: at(modified.Add(time.Hour)), lastModified: modified},
+		{name: "same second", ifModSince: at(modified), lastModified: modified, want: true},
+		{name: "later date", ifModSince: at(modified.Add(time.Minute)), lastModified: modified, want: true},
+		{name: "earlier date", ifModSince: at(modified.Add(-time.Second)), lastModified: modified},
+		{name: "unparseable date", ifModSince: "yesterday", lastModified: modified},
+		{name: "date without last modified", ifModSince: at(modified)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			if tt.ifModSince != "" {
+				r.Header.Set("If-Modified-Since", tt.ifModSince)
+			}
+			if got := notModified(r, etag, tt.lastModified); got != tt.want {
+				t.Errorf("notModified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachedListRevalidates(t *testing.T) {
+	router, store, _ := newTestServer(t)
+	if _, err := store.CreateUser("Dana", "dana@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status %d, ETag %q", rec.Code, etag)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	r.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("revalidation: status %d, %d body bytes, want bodiless 304", rec.Code, rec.Body.Len())
+	}
+
+	if _, err := store.CreateUser("Erin", "erin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("after a change: status %d, want 200", rec.Code)
+	}
+}
+
+func TestCachedListIgnoresIfModifiedSince(t *testing.T) {
+	router, store, _ := newTestServer(t)
+
+	rec := serve(router, http.MethodGet, "/api/v1/users", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if lm := rec.Header().Get("Last-Modified"); lm != "" {
+		t.Errorf("list sent Last-Modified %q", lm)
+	}
+
+	// Deleting a row leaves every remaining updated_at as it was, so only
+	// the ETag can tell this client its copy is stale.
+	if err := store.DeleteUser(2); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	r.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("If-Modified-Since after a delete: status %d, want 200", rec.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+	r.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("single item If-Modified-Since: status %d, want 304", rec.Code)
+	}
+}
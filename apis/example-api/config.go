@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"dinky-shared/config"
+	"dinky-shared/logging"
+)
+
+// Config holds the runtime settings of the API, layered by config.Load.
+type Config struct {
+	Port            int             `json:"port"`
+	GRPCPort        int             `json:"grpc_port"`
+	DBPath          string          `json:"db_path"`
+	UploadDir       string          `json:"upload_dir"`
+	BackupDir       string          `json:"backup_dir"`
+	MaxUploadBytes  int64           `json:"max_upload_bytes"`
+	LogLevel        string          `json:"log_level"`
+	ReadTimeout     config.Duration `json:"read_timeout"`
+	WriteTimeout    config.Duration `json:"write_timeout"`
+	IdleTimeout     config.Duration `json:"idle_timeout"`
+	ShutdownTimeout config.Duration `json:"shutdown_timeout"`
+	// TransferTimeout replaces the read/write timeouts for file uploads and
+	// downloads, which legitimately take longer than ordinary requests.
+	TransferTimeout config.Duration `json:"transfer_timeout"`
+	// CacheMaxAge is the max-age sent on cacheable GET responses; zero sends
+	// no-cache so every reuse is revalidated.
+	CacheMaxAge config.Duration `json:"cache_max_age"`
+	// BackupInterval schedules database snapshots; zero turns scheduling off
+	// and leaves only manual backups. BackupKeep snapshots are retained.
+	BackupInterval config.Duration `json:"backup_interval"`
+	BackupKeep     int             `json:"backup_keep"`
+	// LegacySunset is when the unversioned routes stop being served in favour
+	// of /api/v1.
+	LegacySunset Date `json:"legacy_sunset"`
+	// AdminToken unlocks the /admin endpoints as a bearer token. Empty
+	// leaves them disabled, since they can wipe or restore the database.
+	AdminToken string `json:"admin_token"`
+}
+
+// Date is a calendar day that reads from JSON as a string such as "2027-04-30".
+type Date struct {
+	time.Time
+}
+
+const dateLayout = "2006-01-02"
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("date must be a string like \"2027-04-30\": %w", err)
+	}
+	v, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return err
+	}
+	d.Time = v
+	return nil
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format(dateLayout))
+}
+
+func defaultConfig() Config {
+	return Config{
+		Port:            8080,
+		GRPCPort:        9090,
+		DBPath:          "data/example-api.db",
+		UploadDir:       "data/uploads",
+		BackupDir:       "data/backups",
+		MaxUploadBytes:  100 << 20,
+		LogLevel:        "info",
+		ReadTimeout:     config.Duration{Duration: 15 * time.Second},
+		WriteTimeout:    config.Duration{Duration: 30 * time.Second},
+		IdleTimeout:     config.Duration{Duration: 60 * time.Second},
+		ShutdownTimeout: config.Duration{Duration: 20 * time.Second},
+		TransferTimeout: config.Duration{Duration: 10 * time.Minute},
+		BackupInterval:  config.Duration{Duration: 24 * time.Hour},
+		BackupKeep:      7,
+		LegacySunset:    Date{time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)},
+	}
+}
+
+// loadConfig builds the configuration and fails on any invalid value rather
+// than silently falling back to a default.
+func loadConfig() (Config, error) {
+	cfg := defaultConfig()
+	if err := config.Load(&cfg, cfg.env()); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// env names the environment variable behind each setting.
+func (c *Config) env() config.Env {
+	return config.Env{
+		"PORT":             config.IntVar(&c.Port),
+		"GRPC_PORT":        config.IntVar(&c.GRPCPort),
+		"DB_PATH":          config.StringVar(&c.DBPath),
+		"LOG_LEVEL":        config.StringVar(&c.LogLevel),
+		"UPLOAD_DIR":       config.StringVar(&c.UploadDir),
+		"BACKUP_DIR":       config.StringVar(&c.BackupDir),
+		"ADMIN_TOKEN":      config.StringVar(&c.AdminToken),
+		"BACKUP_KEEP":      config.IntVar(&c.BackupKeep),
+		"MAX_UPLOAD_BYTES": config.Int64Var(&c.MaxUploadBytes),
+		"LEGACY_SUNSET": func(v string) error {
+			t, err := time.Parse(dateLayout, v)
+			if err != nil {
+				return err
+			}
+			c.LegacySunset = Date{t}
+			return nil
+		},
+		"READ_TIMEOUT":     config.DurationVar(&c.ReadTimeout),
+		"WRITE_TIMEOUT":    config.DurationVar(&c.WriteTimeout),
+		"IDLE_TIMEOUT":     config.DurationVar(&c.IdleTimeout),
+		"SHUTDOWN_TIMEOUT": config.DurationVar(&c.ShutdownTimeout),
+		"TRANSFER_TIMEOUT": config.DurationVar(&c.TransferTimeout),
+		"CACHE_MAX_AGE":    config.DurationVar(&c.CacheMaxAge),
+		"BACKUP_INTERVAL":  config.DurationVar(&c.BackupInterval),
+	}
+}
+
+func (c Config) validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("port %d is out of range", c.Port)
+	}
+	if c.GRPCPort < 1 || c.GRPCPort > 65535 {
+		return fmt.Errorf("grpc_port %d is out of range", c.GRPCPort)
+	}
+	if c.GRPCPort == c.Port {
+		return fmt.Errorf("grpc_port and port must differ (both %d)", c.Port)
+	}
+	if c.DBPath == "" {
+		return fmt.Errorf("db_path must not be empty")
+	}
+	if c.UploadDir == "" {
+		return fmt.Errorf("upload_dir must not be empty")
+	}
+	if c.MaxUploadBytes <= 0 {
+		return fmt.Errorf("max_upload_bytes must be positive")
+	}
+	if !logging.Valid(c.LogLevel) {
+		return fmt.Errorf("log_level %q must be one of debug, info, warn, error", c.LogLevel)
+	}
+	for name, d := range map[string]config.Duration{
+		"read_timeout":     c.ReadTimeout,
+		"write_timeout":    c.WriteTimeout,
+		"idle_timeout":     c.IdleTimeout,
+		"shutdown_timeout": c.ShutdownTimeout,
+		"transfer_timeout": c.TransferTimeout,
+	} {
+		if d.Duration <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+	if c.CacheMaxAge.Duration < 0 {
+		return fmt.Errorf("cache_max_age must not be negative")
+	}
+	if c.BackupDir == "" {
+		return fmt.Errorf("backup_dir must not be empty")
+	}
+	if c.BackupInterval.Duration < 0 {
+		return fmt.Errorf("backup_interval must not be negative")
+	}
+	if c.BackupKeep < 1 {
+		return fmt.Errorf("backup_keep must be at least 1")
+	}
+	if c.AdminToken != "" && len(c.AdminToken) < 16 {
+		return fmt.Errorf("admin_token must be at least 16 characters")
+	}
+	return nil
+}
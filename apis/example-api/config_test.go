@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{name: "known keys", file: `{"port": 9000, "write_timeout": "45s"}`},
+		{name: "misspelled key", file: `{"write_timout": "45s"}`, wantErr: `unknown field "write_timout"`},
+		{name: "wrong type", file: `{"port": "9000"}`, wantErr: "port"},
+		{name: "bad duration", file: `{"write_timeout": 45}`, wantErr: "duration"},
+		{name: "invalid value", file: `{"port": 70000}`, wantErr: "out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("CONFIG_FILE", path)
+
+			cfg, err := loadConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Port != 9000 || cfg.WriteTimeout.Duration != 45*time.Second {
+				t.Errorf("port %d, write_timeout %s; want 9000, 45s", cfg.Port, cfg.WriteTimeout)
+			}
+		})
+	}
+}
+
+func TestLoadConfigEnvOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"port": 9000}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("PORT", "9001")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9001 {
+		t.Errorf("port %d, want the environment's 9001", cfg.Port)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	sseDefaultInterval = time.Second
+	sseMinInterval     = 10 * time.Millisecond
+	sseMaxInterval     = time.Minute
+	sseMaxPayload      = 64 * 1024
+	// sseWriteWait is how long one event may take to write before the client
+	// is treated as a slow consumer and disconnected.
+	sseWriteWait = 10 * time.Second
+)
+
+var (
+	sseConnectionsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sse_connections_active",
+		Help: "Open Server-Sent Events streams.",
+	})
+
+	sseEventsSentTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sse_events_sent_total",
+		Help: "Server-Sent Events written to clients.",
+	})
+
+	sseDisconnectsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sse_disconnects_total",
+		Help: "Closed SSE streams, by reason (client, slow_consumer, complete, shutdown).",
+	}, []string{"reason"})
+)
+
+type sseEvent struct {
+	Seq       int       `json:"seq"`
+	Timestamp time.Time `json:"timestamp"`
+	Payload   string    `json:"payload,omitempty"`
+}
+
+// eventsHandler serves /events, streaming a JSON event every interval.
+// Query parameters: interval (duration, default 1s), size (payload bytes,
+// default 0) and count (stop after N events, default unlimited).
+func eventsHandler(shutdown <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		interval, size, count, errs := parseEventParams(r)
+		if len(errs) > 0 {
+			respond.ErrorDetails(w, http.StatusBadRequest, codeBadRequest, "invalid query parameters", errs)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		// Ask Traefik/nginx not to buffer the stream.
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			logging.Errorf("SSE stream cannot be flushed: %v", err)
+			return
+		}
+
+		sseConnectionsActive.Inc()
+		defer sseConnectionsActive.Dec()
+
+		payload := strings.Repeat("x", size)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for seq := 1; count == 0 || seq <= count; seq++ {
+			select {
+			case <-r.Context().Done():
+				sseDisconnectsTotal.WithLabelValues("client").Inc()
+				return
+			case <-shutdown:
+				sseDisconnectsTotal.WithLabelValues("shutdown").Inc()
+				return
+			case <-ticker.C:
+			}
+
+			data, _ := json.Marshal(sseEvent{Seq: seq, Timestamp: time.Now().UTC(), Payload: payload})
+			rc.SetWriteDeadline(time.Now().Add(sseWriteWait))
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: tick\ndata: %s\n\n", seq, data); err != nil {
+				sseDisconnectsTotal.WithLabelValues("slow_consumer").Inc()
+				logging.Debugf("SSE write failed after %d events: %v", seq-1, err)
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				sseDisconnectsTotal.WithLabelValues("slow_consumer").Inc()
+				return
+			}
+			sseEventsSentTotal.Inc()
+		}
+		sseDisconnectsTotal.WithLabelValues("complete").Inc()
+	}
+}
+
+func parseEventParams(r *http.Request) (time.Duration, int, int, []respond.FieldError) {
+	q := r.URL.Query()
+	interval, size, count := sseDefaultInterval, 0, 0
+	var errs []respond.FieldError
+
+	if v := q.Get("interval"); v != "" {
+		d, err := parseDelay(v)
+		if err != nil || d < sseMinInterval || d > sseMaxInterval {
+			errs = append(errs, respond.FieldError{Field: "interval", Message: fmt.Sprintf("must be a duration between %s and %s", sseMinInterval, sseMaxInterval)})
+		}
+		interval = d
+	}
+	if v := q.Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > sseMaxPayload {
+			errs = append(errs, respond.FieldError{Field: "size", Message: fmt.Sprintf("must be an integer between 0 and %d", sseMaxPayload)})
+		}
+		size = n
+	}
+	if v := q.Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs = append(errs, respond.FieldError{Field: "count", Message: "must be a non-negative integer"})
+		}
+		count = n
+	}
+	return interval, size, count, errs
+}
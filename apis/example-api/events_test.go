@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvent reads one SSE event and returns its data line.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return data
+		}
+		if v, ok := strings.CutPrefix(line, "data: "); ok {
+			data = v
+		}
+	}
+}
+
+func TestEventsStopOnShutdown(t *testing.T) {
+	shutdown := make(chan struct{})
+	srv := httptest.NewUnstartedServer(eventsHandler(shutdown))
+	srv.Config.RegisterOnShutdown(func() { close(shutdown) })
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/?interval=10ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	body := bufio.NewReader(resp.Body)
+	if data := readEvent(t, body); !strings.Contains(data, `"seq":1`) {
+		t.Fatalf("first event %s", data)
+	}
+
+	// Shutdown waits for active handlers, so it only returns in time if the
+	// stream ends on its own when shutdown starts.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := srv.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(body)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("stream did not end cleanly: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream still open after shutdown")
+	}
+}
+
+func TestEventsCount(t *testing.T) {
+	srv := httptest.NewServer(eventsHandler(make(chan struct{})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/?interval=10ms&count=3&size=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body := bufio.NewReader(resp.Body)
+	for seq := 1; seq <= 3; seq++ {
+		data := readEvent(t, body)
+		if !strings.Contains(data, fmt.Sprintf(`"seq":%d,`, seq)) || !strings.Contains(data, `"payload":"xxxx"`) {
+			t.Errorf("event %d: %s", seq, data)
+		}
+	}
+	if rest, err := io.ReadAll(body); err != nil || len(rest) != 0 {
+		t.Errorf("after count events: %q, %v; want the stream closed", rest, err)
+	}
+}
+
+func TestEventsRejectBadParams(t *testing.T) {
+	h := eventsHandler(make(chan struct{}))
+	for _, query := range []string{"interval=1ms", "interval=2m", "interval=soon", "size=-1", "size=70000", "count=-2", "count=x"} {
+		rec := serve(h, http.MethodGet, "/?"+query, "")
+		if rec.Code != http.StatusBadRequest || errorCode(rec) != codeBadRequest {
+			t.Errorf("%s: status %d, code %q; want 400 %s", query, rec.Code, errorCode(rec), codeBadRequest)
+		}
+	}
+}
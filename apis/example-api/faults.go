@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dinky-shared/config"
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// maxInjectedDelay keeps injected latency below the server's write timeout,
+// leaving a sixth of it for the handler, so a delayed request still gets its
+// response instead of a dropped connection. The default 30s timeout allows
+// 25s.
+func maxInjectedDelay(writeTimeout time.Duration) time.Duration {
+	return writeTimeout - writeTimeout/6
+}
+
+var faultsInjectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "faults_injected_total",
+	Help: "Faults injected into responses, by type (delay/error) and source (request/baseline).",
+}, []string{"type", "source"})
+
+// FaultConfig is the baseline fault profile applied to every request that is
+// not exempt from injection.
+type FaultConfig struct {
+	Latency     config.Duration `json:"latency"`
+	Jitter      config.Duration `json:"jitter"`
+	ErrorRate   float64         `json:"error_rate"`
+	ErrorStatus int             `json:"error_status"`
+}
+
+// faultInjector holds the current baseline profile.
+type faultInjector struct {
+	mu       sync.RWMutex
+	cfg      FaultConfig
+	maxDelay time.Duration
+}
+
+func newFaultInjector(writeTimeout time.Duration) *faultInjector {
+	return &faultInjector{
+		cfg:      FaultConfig{ErrorStatus: http.StatusInternalServerError},
+		maxDelay: maxInjectedDelay(writeTimeout),
+	}
+}
+
+func (f *faultInjector) get() FaultConfig {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.cfg
+}
+
+func (f *faultInjector) set(cfg FaultConfig) {
+	f.mu.Lock()
+	f.cfg = cfg
+	f.mu.Unlock()
+}
+
+// faultExempt lists path prefixes that never get faults, so health checks,
+// scraping and the fault admin API itself keep working.
+var faultExempt = []string{"/health", "/readyz", "/metrics", "/admin/"}
+
+// middleware injects faults into a request. Per-request faults come from the
+// X-Inject-Delay / X-Inject-Status headers or the inject_delay /
+// inject_status query parameters and take precedence over the baseline.
+func (f *faultInjector) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range faultExempt {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		delay, status, errs := f.requestedFaults(r)
+		if len(errs) > 0 {
+			respond.ErrorDetails(w, http.StatusBadRequest, codeBadRequest, "invalid fault injection parameters", errs)
+			return
+		}
+
+		source := "request"
+		if delay == 0 && status == 0 {
+			source = "baseline"
+			delay, status = f.baselineFaults()
+		}
+
+		if delay > 0 {
+			faultsInjectedTotal.WithLabelValues("delay", source).Inc()
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if status != 0 {
+			faultsInjectedTotal.WithLabelValues("error", source).Inc()
+			respond.Error(w, status, codeInjectedFault, "fault injected by example-api")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// baselineFaults rolls the configured baseline profile for one request.
+func (f *faultInjector) baselineFaults() (time.Duration, int) {
+	cfg := f.get()
+	delay := cfg.Latency.Duration
+	if cfg.Jitter.Duration > 0 {
+		delay += time.Duration(rand.Int63n(int64(cfg.Jitter.Duration)))
+	}
+	var status int
+	if cfg.ErrorRate > 0 && rand.Float64() < cfg.ErrorRate {
+		status = cfg.ErrorStatus
+	}
+	return delay, status
+}
+
+func (f *faultInjector) requestedFaults(r *http.Request) (time.Duration, int, []respond.FieldError) {
+	var (
+		delay  time.Duration
+		status int
+		errs   []respond.FieldError
+	)
+
+	if v := headerOrQuery(r, "X-Inject-Delay", "inject_delay"); v != "" {
+		d, err := parseDelay(v)
+		if err != nil || d < 0 || d > f.maxDelay {
+			errs = append(errs, respond.FieldError{Field: "inject_delay", Message: fmt.Sprintf("must be a duration (e.g. 250ms) or milliseconds, at most %s", f.maxDelay)})
+		}
+		delay = d
+	}
+	if v := headerOrQuery(r, "X-Inject-Status", "inject_status"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 400 || n > 599 {
+			errs = append(errs, respond.FieldError{Field: "inject_status", Message: "must be an HTTP error status between 400 and 599"})
+		}
+		status = n
+	}
+	return delay, status, errs
+}
+
+func headerOrQuery(r *http.Request, header, param string) string {
+	if v := r.Header.Get(header); v != "" {
+		return v
+	}
+	return r.URL.Query().Get(param)
+}
+
+// parseDelay accepts Go durations ("1.5s") or bare milliseconds ("1500").
+func parseDelay(v string) (time.Duration, error) {
+	if ms, err := strconv.Atoi(v); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	return time.ParseDuration(v)
+}
+
+func getFaults(f *faultInjector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respond.JSON(w, http.StatusOK, f.get())
+	}
+}
+
+// putFaults replaces the baseline profile. Send an empty object to disable
+// baseline faults.
+func putFaults(f *faultInjector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var cfg FaultConfig
+		if !decodeJSON(w, r, &cfg) {
+			return
+		}
+		if cfg.ErrorStatus == 0 {
+			cfg.ErrorStatus = http.StatusInternalServerError
+		}
+
+		var errs []respond.FieldError
+		if cfg.Latency.Duration < 0 || cfg.Jitter.Duration < 0 || cfg.Latency.Duration+cfg.Jitter.Duration > f.maxDelay {
+			errs = append(errs, respond.FieldError{Field: "latency", Message: fmt.Sprintf("latency plus jitter must be between 0 and %s", f.maxDelay)})
+		}
+		if cfg.ErrorRate < 0 || cfg.ErrorRate > 1 {
+			errs = append(errs, respond.FieldError{Field: "error_rate", Message: "must be between 0 and 1"})
+		}
+		if cfg.ErrorStatus < 400 || cfg.ErrorStatus > 599 {
+			errs = append(errs, respond.FieldError{Field: "error_status", Message: "must be an HTTP error status between 400 and 599"})
+		}
+		if len(errs) > 0 {
+			writeValidationError(w, errs)
+			return
+		}
+
+		f.set(cfg)
+		logging.Infof("baseline faults set: latency=%s jitter=%s error_rate=%.2f error_status=%d",
+			cfg.Latency, cfg.Jitter, cfg.ErrorRate, cfg.ErrorStatus)
+		respond.JSON(w, http.StatusOK, cfg)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxInjectedDelay(t *testing.T) {
+	tests := []struct {
+		writeTimeout, want time.Duration
+	}{
+		{30 * time.Second, 25 * time.Second},
+		{10 * time.Second, 8333333334 * time.Nanosecond},
+		{6 * time.Second, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := maxInjectedDelay(tt.writeTimeout); got != tt.want {
+			t.Errorf("maxInjectedDelay(%s) = %s, want %s", tt.writeTimeout, got, tt.want)
+		}
+		if got := maxInjectedDelay(tt.writeTimeout); got >= tt.writeTimeout {
+			t.Errorf("maxInjectedDelay(%s) = %s is not below the write timeout", tt.writeTimeout, got)
+		}
+	}
+}
+
+func TestInjectedDelayFollowsWriteTimeout(t *testing.T) {
+	f := newFaultInjector(10 * time.Second)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	h := f.middleware(ok)
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"inject_delay=20s", http.StatusBadRequest},
+		{"inject_delay=10s", http.StatusBadRequest},
+		{"inject_delay=-1s", http.StatusBadRequest},
+		{"inject_delay=1ms", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/hello?"+tt.query, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.query, rec.Code, tt.status)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	putFaults(f)(rec, httptest.NewRequest(http.MethodPut, "/admin/faults", strings.NewReader(`{"latency":"8s","jitter":"2s"}`)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("baseline of 10s against a 10s write timeout: status %d, want 422", rec.Code)
+	}
+}
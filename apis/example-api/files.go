@@ -0,0 +1,270 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var fileTransferBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "file_transfer_bytes_total",
+	Help: "Bytes transferred through the file endpoints, by direction (upload/download).",
+}, []string{"direction"})
+
+// fileIDPattern matches the IDs generated by newFileID, so path input never
+// reaches the filesystem unchecked.
+var fileIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// fileService stores uploaded content under dir and its metadata in the store.
+type fileService struct {
+	store           *Store
+	dir             string
+	maxBytes        int64
+	transferTimeout time.Duration
+}
+
+func newFileService(store *Store, cfg Config) (*fileService, error) {
+	if err := os.MkdirAll(cfg.UploadDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create upload dir: %w", err)
+	}
+	return &fileService{
+		store:           store,
+		dir:             cfg.UploadDir,
+		maxBytes:        cfg.MaxUploadBytes,
+		transferTimeout: cfg.TransferTimeout.Duration,
+	}, nil
+}
+
+// extendDeadlines swaps the server-wide read/write timeouts for the longer
+// transfer timeout on this request only.
+func (fs *fileService) extendDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(fs.transferTimeout)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		logging.Debugf("cannot extend read deadline: %v", err)
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil {
+		logging.Debugf("cannot extend write deadline: %v", err)
+	}
+}
+
+// upload streams the first "file" part of a multipart body to disk without
+// buffering it in memory.
+func (fs *fileService) upload(w http.ResponseWriter, r *http.Request) {
+	fs.extendDeadlines(w)
+	// Allow some headroom for multipart boundaries and part headers.
+	r.Body = http.MaxBytesReader(w, r.Body, fs.maxBytes+64<<10)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		respond.Error(w, http.StatusBadRequest, codeBadRequest, "request must be multipart/form-data")
+		return
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			respond.ErrorDetails(w, http.StatusUnprocessableEntity, codeValidationFailed, "request validation failed",
+				[]respond.FieldError{{Field: "file", Message: "is required"}})
+			return
+		}
+		if err != nil {
+			fs.writeUploadError(w, err)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		meta, err := fs.save(part)
+		part.Close()
+		if err != nil {
+			fs.writeUploadError(w, err)
+			return
+		}
+		w.Header().Set("Location", "/api/v1/files/"+meta.ID)
+		respond.JSON(w, http.StatusCreated, meta)
+		return
+	}
+}
+
+var errTooLarge = errors.New("file too large")
+
+func (fs *fileService) save(part *multipart.Part) (FileMeta, error) {
+	id, err := newFileID()
+	if err != nil {
+		return FileMeta{}, err
+	}
+	path := filepath.Join(fs.dir, id)
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return FileMeta{}, err
+	}
+
+	hash := sha256.New()
+	// Read one byte past the limit to detect oversized files.
+	n, err := io.Copy(io.MultiWriter(out, hash), io.LimitReader(part, fs.maxBytes+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > fs.maxBytes {
+		err = errTooLarge
+	}
+	if err != nil {
+		os.Remove(path)
+		return FileMeta{}, err
+	}
+	fileTransferBytes.WithLabelValues("upload").Add(float64(n))
+
+	name, contentType := "upload", "application/octet-stream"
+	if part.FileName() != "" {
+		name = filepath.Base(part.FileName())
+	}
+	if ct := part.Header.Get("Content-Type"); ct != "" {
+		contentType = ct
+	}
+
+	meta := FileMeta{
+		ID:          id,
+		Name:        name,
+		Size:        n,
+		ContentType: contentType,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := fs.store.CreateFile(meta); err != nil {
+		os.Remove(path)
+		return FileMeta{}, err
+	}
+	return meta, nil
+}
+
+func (fs *fileService) writeUploadError(w http.ResponseWriter, err error) {
+	var maxErr *http.MaxBytesError
+	if errors.Is(err, errTooLarge) || errors.As(err, &maxErr) {
+		respond.ErrorDetails(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, "upload exceeds the size limit",
+			[]respond.FieldError{{Field: "file", Message: fmt.Sprintf("must be at most %d bytes", fs.maxBytes)}})
+		return
+	}
+	logging.Errorf("upload failed: %v", err)
+	respond.Error(w, http.StatusInternalServerError, codeInternal, "upload failed")
+}
+
+func (fs *fileService) list(w http.ResponseWriter, r *http.Request) {
+	files, err := fs.store.ListFiles()
+	if err != nil {
+		fs.writeFileError(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, files)
+}
+
+// download streams a file. http.ServeContent handles Range, If-Range and
+// If-Modified-Since, so partial and resumed downloads work.
+func (fs *fileService) download(w http.ResponseWriter, r *http.Request) {
+	meta, ok := fs.lookup(w, r)
+	if !ok {
+		return
+	}
+	f, err := os.Open(filepath.Join(fs.dir, meta.ID))
+	if err != nil {
+		fs.writeFileError(w, err)
+		return
+	}
+	defer f.Close()
+
+	fs.extendDeadlines(w)
+	w.Header().Set("Content-Type", meta.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": meta.Name}))
+	w.Header().Set("ETag", `"`+meta.SHA256+`"`)
+
+	cw := &countingWriter{ResponseWriter: w}
+	http.ServeContent(cw, r, meta.Name, meta.CreatedAt, f)
+	fileTransferBytes.WithLabelValues("download").Add(float64(cw.n))
+}
+
+func (fs *fileService) metadata(w http.ResponseWriter, r *http.Request) {
+	if meta, ok := fs.lookup(w, r); ok {
+		respond.JSON(w, http.StatusOK, meta)
+	}
+}
+
+func (fs *fileService) remove(w http.ResponseWriter, r *http.Request) {
+	meta, ok := fs.lookup(w, r)
+	if !ok {
+		return
+	}
+	if err := fs.store.DeleteFile(meta.ID); err != nil {
+		fs.writeFileError(w, err)
+		return
+	}
+	if err := os.Remove(filepath.Join(fs.dir, meta.ID)); err != nil && !os.IsNotExist(err) {
+		logging.Warnf("file %s deleted from store but not from disk: %v", meta.ID, err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (fs *fileService) lookup(w http.ResponseWriter, r *http.Request) (FileMeta, bool) {
+	id := mux.Vars(r)["id"]
+	if !fileIDPattern.MatchString(id) {
+		respond.Error(w, http.StatusBadRequest, codeBadRequest, "file id must be 32 lowercase hex characters")
+		return FileMeta{}, false
+	}
+	meta, err := fs.store.GetFile(id)
+	if err != nil {
+		fs.writeFileError(w, err)
+		return FileMeta{}, false
+	}
+	return meta, true
+}
+
+func (fs *fileService) writeFileError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errNotFound) || os.IsNotExist(err) {
+		respond.Error(w, http.StatusNotFound, codeNotFound, "file not found")
+		return
+	}
+	logging.Errorf("file error: %v", err)
+	respond.Error(w, http.StatusInternalServerError, codeInternal, "internal error")
+}
+
+func newFileID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// countingWriter counts body bytes for the transfer metric.
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// uploadRequest builds a multipart POST /api/v1/files carrying content in a
+// part named field.
+func uploadRequest(t *testing.T, field, content string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile(field, "notes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/files", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestFileDownloadRanges(t *testing.T) {
+	router, _, _ := newTestServer(t)
+	const content = "hello, ranged world"
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, uploadRequest(t, "file", content))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("upload: status %d (body %s)", rec.Code, rec.Body)
+	}
+	var meta FileMeta
+	if err := json.Unmarshal(rec.Body.Bytes(), &meta); err != nil {
+		t.Fatal(err)
+	}
+	location := rec.Header().Get("Location")
+	if meta.Size != int64(len(content)) || location != "/api/v1/files/"+meta.ID {
+		t.Fatalf("meta %+v, Location %q", meta, location)
+	}
+
+	tests := []struct {
+		name, method, rangeHeader string
+		status                    int
+		body, contentRange        string
+	}{
+		{name: "whole file", method: "GET", status: http.StatusOK, body: content},
+		{name: "middle", method: "GET", rangeHeader: "bytes=7-12", status: http.StatusPartialContent, body: "ranged", contentRange: "bytes 7-12/19"},
+		{name: "suffix", method: "GET", rangeHeader: "bytes=-5", status: http.StatusPartialContent, body: "world", contentRange: "bytes 14-18/19"},
+		{name: "open ended", method: "GET", rangeHeader: "bytes=14-", status: http.StatusPartialContent, body: "world", contentRange: "bytes 14-18/19"},
+		{name: "unsatisfiable", method: "GET", rangeHeader: "bytes=50-60", status: http.StatusRequestedRangeNotSatisfiable, contentRange: "bytes */19"},
+		{name: "head", method: "HEAD", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, location, nil)
+			if tt.rangeHeader != "" {
+				r.Header.Set("Range", tt.rangeHeader)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, r)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status != http.StatusRequestedRangeNotSatisfiable && rec.Body.String() != tt.body {
+				t.Errorf("body %q, want %q", rec.Body, tt.body)
+			}
+			if got := rec.Header().Get("Content-Range"); got != tt.contentRange {
+				t.Errorf("Content-Range %q, want %q", got, tt.contentRange)
+			}
+			if tt.method == "HEAD" && rec.Header().Get("Content-Length") != "19" {
+				t.Errorf("HEAD Content-Length %q, want 19", rec.Header().Get("Content-Length"))
+			}
+			if got := rec.Header().Get("Accept-Ranges"); tt.status == http.StatusOK && got != "bytes" {
+				t.Errorf("Accept-Ranges %q, want bytes", got)
+			}
+		})
+	}
+}
+
+func TestFileUploadRejections(t *testing.T) {
+	const limit = 16
+	var uploadDir string
+	router, _, _ := newTestServerWith(t, func(cfg *Config) {
+		cfg.MaxUploadBytes = limit
+		uploadDir = cfg.UploadDir
+	})
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		code   string
+	}{
+		{name: "at the limit", req: uploadRequest(t, "file", strings.Repeat("a", limit)), status: http.StatusCreated},
+		{name: "one byte over", req: uploadRequest(t, "file", strings.Repeat("a", limit+1)), status: http.StatusRequestEntityTooLarge, code: codePayloadTooLarge},
+		{name: "far over", req: uploadRequest(t, "file", strings.Repeat("a", 1<<20)), status: http.StatusRequestEntityTooLarge, code: codePayloadTooLarge},
+		{name: "no file part", req: uploadRequest(t, "attachment", "hi"), status: http.StatusUnprocessableEntity, code: codeValidationFailed},
+		{name: "not multipart", req: httptest.NewRequest(http.MethodPost, "/api/v1/files", strings.NewReader("hi")), status: http.StatusBadRequest, code: codeBadRequest},
+		{name: "bad id", req: httptest.NewRequest(http.MethodGet, "/api/v1/files/XYZ", nil), status: http.StatusBadRequest, code: codeBadRequest},
+		{name: "unknown id", req: httptest.NewRequest(http.MethodGet, "/api/v1/files/"+strings.Repeat("0", 32), nil), status: http.StatusNotFound, code: codeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d (body %s)", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code != "" && errorCode(rec) != tt.code {
+				t.Errorf("code %q, want %q", errorCode(rec), tt.code)
+			}
+		})
+	}
+
+	// Only the upload at the limit may leave a file behind.
+	entries, err := os.ReadDir(uploadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d files in the upload dir, want 1", len(entries))
+	}
+}
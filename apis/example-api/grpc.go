@@ -0,0 +1,168 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/users/v1/users.proto
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+	usersv1 "example-api/proto/users/v1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	grpcHandledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Total gRPC calls completed on the server, by method and status code.",
+	}, []string{"method", "code"})
+
+	grpcHandlingSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "gRPC call latency in seconds, by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// newGRPCServer builds the gRPC server exposing UserService, with server
+// reflection enabled so tools like grpcurl can discover it.
+func newGRPCServer(store *Store) *grpc.Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(grpcMetricsInterceptor))
+	usersv1.RegisterUserServiceServer(srv, &userServer{store: store})
+	reflection.Register(srv)
+	return srv
+}
+
+func grpcMetricsInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	code := status.Code(err)
+
+	grpcHandlingSeconds.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+	grpcHandledTotal.WithLabelValues(info.FullMethod, code.String()).Inc()
+	logging.Debugf("gRPC %s %s %s", info.FullMethod, code, time.Since(start))
+	return resp, err
+}
+
+// userServer implements usersv1.UserServiceServer on top of the same store
+// and validation rules as the REST handlers.
+type userServer struct {
+	usersv1.UnimplementedUserServiceServer
+	store *Store
+}
+
+func (s *userServer) ListUsers(ctx context.Context, req *usersv1.ListUsersRequest) (*usersv1.ListUsersResponse, error) {
+	q := UserQuery{
+		Name:   req.GetName(),
+		Email:  req.GetEmail(),
+		Sort:   req.GetSort(),
+		Limit:  int(req.GetLimit()),
+		Offset: int(req.GetOffset()),
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultPageLimit
+	}
+	if errs := validateUserQuery(q); len(errs) > 0 {
+		return nil, invalidArgument(errs)
+	}
+
+	users, total, err := s.store.ListUsers(q)
+	if err != nil {
+		return nil, grpcStoreError(err)
+	}
+	resp := &usersv1.ListUsersResponse{Total: int32(total)}
+	for _, u := range users {
+		resp.Users = append(resp.Users, userToProto(u))
+	}
+	return resp, nil
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *usersv1.GetUserRequest) (*usersv1.User, error) {
+	user, err := s.store.GetUser(req.GetId())
+	if err != nil {
+		return nil, grpcStoreError(err)
+	}
+	return userToProto(user), nil
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *usersv1.CreateUserRequest) (*usersv1.User, error) {
+	name, email := req.GetName(), req.GetEmail()
+	if errs := validateUser(userRequest{Name: &name, Email: &email}, false); len(errs) > 0 {
+		return nil, invalidArgument(errs)
+	}
+	user, err := s.store.CreateUser(name, email)
+	if err != nil {
+		return nil, grpcStoreError(err)
+	}
+	return userToProto(user), nil
+}
+
+func (s *userServer) UpdateUser(ctx context.Context, req *usersv1.UpdateUserRequest) (*usersv1.User, error) {
+	if errs := validateUser(userRequest{Name: req.Name, Email: req.Email}, true); len(errs) > 0 {
+		return nil, invalidArgument(errs)
+	}
+	user, err := s.store.GetUser(req.GetId())
+	if err != nil {
+		return nil, grpcStoreError(err)
+	}
+	if req.Name != nil {
+		user.Name = req.GetName()
+	}
+	if req.Email != nil {
+		user.Email = req.GetEmail()
+	}
+	user, err = s.store.UpdateUser(user.ID, user.Name, user.Email)
+	if err != nil {
+		return nil, grpcStoreError(err)
+	}
+	return userToProto(user), nil
+}
+
+func (s *userServer) DeleteUser(ctx context.Context, req *usersv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	if err := s.store.DeleteUser(req.GetId()); err != nil {
+		return nil, grpcStoreError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func userToProto(u User) *usersv1.User {
+	return &usersv1.User{
+		Id:        u.ID,
+		Name:      u.Name,
+		Email:     u.Email,
+		CreatedAt: timestamppb.New(u.CreatedAt),
+		UpdatedAt: timestamppb.New(u.UpdatedAt),
+	}
+}
+
+func invalidArgument(errs []respond.FieldError) error {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Field + " " + e.Message
+	}
+	return status.Error(codes.InvalidArgument, strings.Join(msgs, "; "))
+}
+
+// grpcStoreError is the gRPC counterpart of writeStoreError.
+func grpcStoreError(err error) error {
+	switch {
+	case errors.Is(err, errNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, errEmailTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		logging.Errorf("store error: %v", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
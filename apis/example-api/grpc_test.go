@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	usersv1 "example-api/proto/users/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// newTestGRPCClient serves newGRPCServer over an in-memory listener.
+func newTestGRPCClient(t *testing.T) usersv1.UserServiceClient {
+	t.Helper()
+	_, store, _ := newTestServer(t)
+	lis := bufconn.Listen(1 << 20)
+	srv := newGRPCServer(store)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return usersv1.NewUserServiceClient(conn)
+}
+
+func TestGRPCErrors(t *testing.T) {
+	client := newTestGRPCClient(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+		msg  string // substring of the status message
+	}{
+		{name: "get missing", code: codes.NotFound, msg: "user not found", call: func() error {
+			_, err := client.GetUser(ctx, &usersv1.GetUserRequest{Id: 99})
+			return err
+		}},
+		{name: "delete missing", code: codes.NotFound, call: func() error {
+			_, err := client.DeleteUser(ctx, &usersv1.DeleteUserRequest{Id: 99})
+			return err
+		}},
+		{name: "update missing", code: codes.NotFound, call: func() error {
+			_, err := client.UpdateUser(ctx, &usersv1.UpdateUserRequest{Id: 99, Name: proto.String("Nobody")})
+			return err
+		}},
+		{name: "create blank name", code: codes.InvalidArgument, msg: "name must not be blank", call: func() error {
+			_, err := client.CreateUser(ctx, &usersv1.CreateUserRequest{Name: " ", Email: "dana@example.com"})
+			return err
+		}},
+		{name: "create bad email", code: codes.InvalidArgument, msg: "email must be a valid email address", call: func() error {
+			_, err := client.CreateUser(ctx, &usersv1.CreateUserRequest{Name: "Dana", Email: "Dana <dana@example.com>"})
+			return err
+		}},
+		{name: "create taken email", code: codes.AlreadyExists, call: func() error {
+			_, err := client.CreateUser(ctx, &usersv1.CreateUserRequest{Name: "Alice", Email: "Alice@example.com"})
+			return err
+		}},
+		{name: "update bad email", code: codes.InvalidArgument, msg: "email", call: func() error {
+			_, err := client.UpdateUser(ctx, &usersv1.UpdateUserRequest{Id: 1, Email: proto.String("nope")})
+			return err
+		}},
+		{name: "list bad sort and limit", code: codes.InvalidArgument, msg: "limit must be", call: func() error {
+			_, err := client.ListUsers(ctx, &usersv1.ListUsersRequest{Limit: 5000, Sort: "password"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(tt.call())
+			if st.Code() != tt.code {
+				t.Fatalf("code %s, want %s (%s)", st.Code(), tt.code, st.Message())
+			}
+			if !strings.Contains(st.Message(), tt.msg) {
+				t.Errorf("message %q, want it to contain %q", st.Message(), tt.msg)
+			}
+		})
+	}
+}
+
+func TestGRPCUserLifecycle(t *testing.T) {
+	client := newTestGRPCClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateUser(ctx, &usersv1.CreateUserRequest{Name: "Dana", Email: "dana@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := client.UpdateUser(ctx, &usersv1.UpdateUserRequest{Id: created.Id, Name: proto.String("Dana B")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "Dana B" || updated.Email != "dana@example.com" {
+		t.Errorf("partial update gave %v", updated)
+	}
+
+	list, err := client.ListUsers(ctx, &usersv1.ListUsersRequest{Name: "dana", Sort: "-id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || len(list.Users) != 1 || list.Users[0].Id != created.Id {
+		t.Errorf("list %v", list)
+	}
+
+	if _, err := client.DeleteUser(ctx, &usersv1.DeleteUserRequest{Id: created.Id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetUser(ctx, &usersv1.GetUserRequest{Id: created.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("get after delete: %v, want NotFound", err)
+	}
+}
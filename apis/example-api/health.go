@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"dinky-shared/health"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newReadinessChecks registers example-api's dependencies: the store must be
+// reachable and fully migrated and the server must not be draining; a
+// missing upload directory only degrades the service.
+func newReadinessChecks(store *Store, uploadDir string, shuttingDown <-chan struct{}) *health.Checks {
+	h := health.New("example-api", prometheus.DefaultRegisterer)
+	h.Register(health.Check{Name: "sqlite", Critical: true, Run: store.Ping})
+	h.Register(health.Check{Name: "migrations", Critical: true, DependsOn: []string{"sqlite"}, Run: func(ctx context.Context) error {
+		version, err := store.SchemaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if version != len(migrations) {
+			return fmt.Errorf("schema at version %d, want %d", version, len(migrations))
+		}
+		return nil
+	}})
+	h.Register(health.Check{Name: "upload_dir", Run: func(ctx context.Context) error {
+		info, err := os.Stat(uploadDir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", uploadDir)
+		}
+		return nil
+	}})
+	h.Register(health.Check{Name: "server", Critical: true, Run: func(ctx context.Context) error {
+		select {
+		case <-shuttingDown:
+			return errors.New("shutting down")
+		default:
+			return nil
+		}
+	}})
+	return h
+}
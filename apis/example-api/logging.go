@@ -0,0 +1,19 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"dinky-shared/httpkit"
+	"dinky-shared/logging"
+)
+
+// requestLogMiddleware logs every request at debug level.
+func requestLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := httpkit.NewStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+		logging.Debugf("%s %s %d %s", r.Method, r.URL.RequestURI(), rec.Status, time.Since(start))
+	})
+}
@@ -1,64 +1,112 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/gorilla/mux"
+	"dinky-shared/logging"
+
+	"google.golang.org/grpc"
 )
 
 func main() {
 	fmt.Println("🚀 Starting Example API")
 
-	router := mux.NewRouter()
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("❌ Invalid configuration: %v", err)
+	}
+	logging.SetLevel(cfg.LogLevel)
+	setCacheMaxAge(cfg.CacheMaxAge.Duration)
 
-	// Health endpoint
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		response := map[string]interface{}{
-			"status":    "healthy",
-			"service":   "example-api",
-			"timestamp": time.Now().Format(time.RFC3339),
-		}
-		json.NewEncoder(w).Encode(response)
-	}).Methods("GET")
-
-	// Hello endpoint
-	router.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		response := map[string]interface{}{
-			"message": "Hello from Example API!",
-			"time":    time.Now().Format(time.RFC3339),
-		}
-		json.NewEncoder(w).Encode(response)
-	}).Methods("GET")
-
-	// Users endpoint (dummy data)
-	router.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		users := []map[string]interface{}{
-			{"id": 1, "name": "Alice", "email": "alice@example.com"},
-			{"id": 2, "name": "Bob", "email": "bob@example.com"},
-			{"id": 3, "name": "Charlie", "email": "charlie@example.com"},
+	store, err := OpenStore(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("❌ Failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	files, err := newFileService(store, cfg)
+	if err != nil {
+		log.Fatalf("❌ Failed to set up file storage: %v", err)
+	}
+
+	backups, err := newBackupService(store, cfg)
+	if err != nil {
+		log.Fatalf("❌ Failed to set up backups: %v", err)
+	}
+
+	// Closed when the server starts shutting down so long-lived streams end
+	// instead of holding up the drain.
+	shuttingDown := make(chan struct{})
+	go backups.schedule(shuttingDown)
+
+	deps := apiDeps{store: store, files: files, wsHub: newWSHub(), shuttingDown: shuttingDown}
+	router := newRouter(cfg, deps, backups)
+
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      router,
+		ReadTimeout:  cfg.ReadTimeout.Duration,
+		WriteTimeout: cfg.WriteTimeout.Duration,
+		IdleTimeout:  cfg.IdleTimeout.Duration,
+	}
+	srv.RegisterOnShutdown(func() { close(shuttingDown) })
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+	if err != nil {
+		log.Fatalf("❌ Failed to listen for gRPC: %v", err)
+	}
+	grpcServer := newGRPCServer(store)
+
+	serverErr := make(chan error, 2)
+	go func() {
+		fmt.Printf("🌐 Example API starting on http://localhost:%d\n", cfg.Port)
+		serverErr <- srv.ListenAndServe()
+	}()
+	go func() {
+		fmt.Printf("📡 gRPC UserService listening on :%d\n", cfg.GRPCPort)
+		serverErr <- grpcServer.Serve(grpcListener)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("❌ Server error: %v", err)
 		}
-		json.NewEncoder(w).Encode(users)
-	}).Methods("GET")
-
-	// Root endpoint
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		response := map[string]interface{}{
-			"message":   "Welcome to Example API",
-			"endpoints": []string{"/health", "/hello", "/users"},
-			"version":   "1.0.0",
+	case <-ctx.Done():
+		fmt.Println("🛑 Shutting down, draining connections...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logging.Warnf("graceful shutdown did not complete: %v", err)
 		}
-		json.NewEncoder(w).Encode(response)
-	}).Methods("GET")
+		stopGRPC(shutdownCtx, grpcServer)
+	}
+	fmt.Println("👋 Example API stopped")
+}
 
-	port := 8080
-	fmt.Printf("🌐 Example API starting on http://localhost:%d\n", port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), router))
+// stopGRPC drains in-flight RPCs, forcing the server closed if ctx expires
+// first.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logging.Warnf("gRPC graceful stop timed out, forcing close")
+		srv.Stop()
+	}
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// requestCount reads one http_requests_total series from the default
+// registry, which the metrics middleware records into.
+func requestCount(t *testing.T, method, route, status string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"method": method, "route": route, "status": status}
+	for _, mf := range families {
+		if mf.GetName() != "http_requests_total" {
+			continue
+		}
+	series:
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if want[l.GetName()] != l.GetValue() {
+					continue series
+				}
+			}
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestMetricsCountUnmatchedRequests(t *testing.T) {
+	router, _, _ := newTestServer(t)
+
+	tests := []struct {
+		method, path, route, status string
+	}{
+		{http.MethodGet, "/nope", "unmatched", "404"},
+		{http.MethodGet, "/api/v1/nope", "unmatched", "404"},
+		{http.MethodPut, "/api/v1/users", "unmatched", "405"},
+		{http.MethodGet, "/api/v1/users/1", "/api/v1/users/{id}", "200"},
+	}
+	for _, tt := range tests {
+		before := requestCount(t, tt.method, tt.route, tt.status)
+		serve(router, tt.method, tt.path, "")
+		if got := requestCount(t, tt.method, tt.route, tt.status); got != before+1 {
+			t.Errorf("%s %s: http_requests_total{route=%q,status=%q} went from %v to %v, want +1",
+				tt.method, tt.path, tt.route, tt.status, before, got)
+		}
+	}
+}
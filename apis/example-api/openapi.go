@@ -0,0 +1,38 @@
+package main
+
+import (
+	"embed"
+	"net/http"
+
+	"dinky-shared/respond"
+)
+
+// The OpenAPI document is maintained by hand next to the handlers; update
+// docs/openapi.json whenever a route or payload changes.
+//
+//go:embed docs/openapi.json docs/swagger.html docs/swagger-ui
+var docsFS embed.FS
+
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := docsFS.ReadFile("docs/openapi.json")
+	if err != nil {
+		respond.Error(w, http.StatusInternalServerError, codeInternal, "OpenAPI document unavailable")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// swaggerAssets serves the vendored Swagger UI files under /docs/swagger-ui/
+// so /docs works without internet access.
+var swaggerAssets = http.FileServer(http.FS(docsFS))
+
+func swaggerUIHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := docsFS.ReadFile("docs/swagger.html")
+	if err != nil {
+		respond.Error(w, http.StatusInternalServerError, codeInternal, "docs page unavailable")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(data)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Author loading strategies for post listings with expand=author. They return
+// the same data but exercise very different query patterns.
+const (
+	strategyJoin     = "join"   // one indexed JOIN
+	strategyNPlusOne = "nplus1" // one query per post to load its author
+	strategyScan     = "scan"   // a JOIN that cannot use any index
+)
+
+var postListQueries = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "post_list_queries",
+	Help:    "SQL queries issued per post listing, by author loading strategy (none/join/nplus1/scan).",
+	Buckets: prometheus.ExponentialBuckets(1, 2, 11),
+}, []string{"strategy"})
+
+type postRequest struct {
+	UserID *int64  `json:"user_id"`
+	Title  *string `json:"title"`
+	Body   *string `json:"body"`
+}
+
+// postListOptions controls whether and how a listing embeds post authors.
+type postListOptions struct {
+	ExpandAuthor bool
+	Strategy     string
+}
+
+func listPosts(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, opts, errs := parsePostQuery(r.URL.Query())
+		if len(errs) > 0 {
+			respond.ErrorDetails(w, http.StatusBadRequest, codeBadRequest, "invalid query parameters", errs)
+			return
+		}
+		writePostList(w, r, store, q, opts)
+	}
+}
+
+// listUserPosts serves GET /users/{id}/posts, answering 404 for unknown
+// users rather than an empty list.
+func listUserPosts(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := userID(w, r)
+		if !ok {
+			return
+		}
+		q, opts, errs := parsePostQuery(r.URL.Query())
+		if len(errs) > 0 {
+			respond.ErrorDetails(w, http.StatusBadRequest, codeBadRequest, "invalid query parameters", errs)
+			return
+		}
+		if _, err := store.GetUser(id); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		q.UserID = id
+		writePostList(w, r, store, q, opts)
+	}
+}
+
+// writePostList runs the listing with the requested author strategy and
+// reports how many SQL queries it took in X-Query-Count.
+func writePostList(w http.ResponseWriter, r *http.Request, store *Store, q PostQuery, opts postListOptions) {
+	var (
+		posts   []Post
+		total   int
+		err     error
+		queries = 2 // count + page
+		label   = "none"
+	)
+	switch {
+	case !opts.ExpandAuthor:
+		posts, total, err = store.ListPosts(q)
+	case opts.Strategy == strategyNPlusOne:
+		label = opts.Strategy
+		posts, total, err = store.ListPosts(q)
+		for i := 0; err == nil && i < len(posts); i++ {
+			var author User
+			author, err = store.GetUser(posts[i].UserID)
+			posts[i].Author = &author
+			queries++
+		}
+	default:
+		label = opts.Strategy
+		posts, total, err = store.ListPostsWithAuthors(q, opts.Strategy != strategyScan)
+	}
+	if err != nil {
+		writePostError(w, err)
+		return
+	}
+	postListQueries.WithLabelValues(label).Observe(float64(queries))
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	w.Header().Set("X-Query-Count", strconv.Itoa(queries))
+	writeCachedList(w, r, posts)
+}
+
+func getPost(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := postID(w, r)
+		if !ok {
+			return
+		}
+		post, err := store.GetPost(id)
+		if err != nil {
+			writePostError(w, err)
+			return
+		}
+		writeCachedJSON(w, r, post, post.UpdatedAt)
+	}
+}
+
+func createPost(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req postRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		savePost(w, store, req)
+	}
+}
+
+// createUserPost serves POST /users/{id}/posts; the author comes from the
+// path and any user_id in the body is ignored.
+func createUserPost(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := userID(w, r)
+		if !ok {
+			return
+		}
+		var req postRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		req.UserID = &id
+		if _, err := store.GetUser(id); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		savePost(w, store, req)
+	}
+}
+
+func savePost(w http.ResponseWriter, store *Store, req postRequest) {
+	if errs := validatePost(req, false); len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+	post, err := store.CreatePost(*req.UserID, *req.Title, *req.Body)
+	if err != nil {
+		writePostError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/posts/"+strconv.FormatInt(post.ID, 10))
+	respond.JSON(w, http.StatusCreated, post)
+}
+
+// replacePost handles PUT, which requires the full representation.
+func replacePost(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := postID(w, r)
+		if !ok {
+			return
+		}
+		var req postRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		if errs := validatePost(req, false); len(errs) > 0 {
+			writeValidationError(w, errs)
+			return
+		}
+		post, err := store.UpdatePost(id, *req.UserID, *req.Title, *req.Body)
+		if err != nil {
+			writePostError(w, err)
+			return
+		}
+		respond.JSON(w, http.StatusOK, post)
+	}
+}
+
+// patchPost handles PATCH, updating only the fields present in the body.
+func patchPost(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := postID(w, r)
+		if !ok {
+			return
+		}
+		var req postRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		if errs := validatePost(req, true); len(errs) > 0 {
+			writeValidationError(w, errs)
+			return
+		}
+		post, err := store.GetPost(id)
+		if err != nil {
+			writePostError(w, err)
+			return
+		}
+		if req.UserID != nil {
+			post.UserID = *req.UserID
+		}
+		if req.Title != nil {
+			post.Title = *req.Title
+		}
+		if req.Body != nil {
+			post.Body = *req.Body
+		}
+		post, err = store.UpdatePost(id, post.UserID, post.Title, post.Body)
+		if err != nil {
+			writePostError(w, err)
+			return
+		}
+		respond.JSON(w, http.StatusOK, post)
+	}
+}
+
+func deletePost(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := postID(w, r)
+		if !ok {
+			return
+		}
+		if err := store.DeletePost(id); err != nil {
+			writePostError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func postID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id < 1 {
+		respond.Error(w, http.StatusBadRequest, codeBadRequest, "post id must be a positive integer")
+		return 0, false
+	}
+	return id, true
+}
+
+func writePostError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errNotFound):
+		respond.Error(w, http.StatusNotFound, codeNotFound, "post not found")
+	case errors.Is(err, errUnknownUser):
+		writeValidationError(w, []respond.FieldError{{Field: "user_id", Message: "does not refer to an existing user"}})
+	default:
+		logging.Errorf("store error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, codeInternal, "internal error")
+	}
+}
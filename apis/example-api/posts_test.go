@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestPostCRUD(t *testing.T) {
+	router, _, _ := newTestServer(t)
+
+	// Steps run in order against one store seeded with users 1-3 and no posts.
+	steps := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{method: "POST", path: "/api/v1/posts", body: `{"user_id":1,"title":"Hello","body":"First"}`, status: http.StatusCreated},
+		{method: "POST", path: "/api/v1/users/2/posts", body: `{"title":"Hi","body":"From Bob"}`, status: http.StatusCreated},
+		{method: "GET", path: "/api/v1/posts/2", status: http.StatusOK},
+		{method: "POST", path: "/api/v1/posts", body: `{"user_id":99,"title":"Ghost","body":"Nobody"}`, status: http.StatusUnprocessableEntity, code: codeValidationFailed},
+		{method: "POST", path: "/api/v1/users/99/posts", body: `{"title":"Ghost","body":"Nobody"}`, status: http.StatusNotFound, code: codeNotFound},
+		{method: "POST", path: "/api/v1/posts", body: `{"user_id":1,"title":""}`, status: http.StatusUnprocessableEntity, code: codeValidationFailed},
+		{method: "GET", path: "/api/v1/users/99/posts", status: http.StatusNotFound, code: codeNotFound},
+		{method: "PUT", path: "/api/v1/posts/1", body: `{"user_id":3,"title":"Moved","body":"To Charlie"}`, status: http.StatusOK},
+		{method: "PUT", path: "/api/v1/posts/99", body: `{"user_id":1,"title":"Missing","body":"Post"}`, status: http.StatusNotFound, code: codeNotFound},
+		{method: "PATCH", path: "/api/v1/posts/1", body: `{"user_id":99}`, status: http.StatusUnprocessableEntity, code: codeValidationFailed},
+		{method: "PATCH", path: "/api/v1/posts/99", body: `{"title":"Missing"}`, status: http.StatusNotFound, code: codeNotFound},
+		{method: "GET", path: "/api/v1/posts/x", status: http.StatusBadRequest, code: codeBadRequest},
+		{method: "DELETE", path: "/api/v1/posts/1", status: http.StatusNoContent},
+		{method: "DELETE", path: "/api/v1/posts/1", status: http.StatusNotFound, code: codeNotFound},
+	}
+	for _, s := range steps {
+		rec := serve(router, s.method, s.path, s.body)
+		if rec.Code != s.status {
+			t.Fatalf("%s %s: status %d, want %d (body %s)", s.method, s.path, rec.Code, s.status, rec.Body)
+		}
+		if s.code != "" && errorCode(rec) != s.code {
+			t.Errorf("%s %s: code %q, want %q", s.method, s.path, errorCode(rec), s.code)
+		}
+	}
+}
+
+func TestDeleteUserCascadesToPosts(t *testing.T) {
+	router, store, _ := newTestServer(t)
+	for _, uid := range []int64{1, 2, 2} {
+		if _, err := store.CreatePost(uid, "Title", "Body"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if rec := serve(router, http.MethodDelete, "/api/v1/users/2", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete user: status %d", rec.Code)
+	}
+
+	posts, total, err := store.ListPosts(PostQuery{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || posts[0].UserID != 1 {
+		t.Errorf("posts left %+v (total %d), want only user 1's", posts, total)
+	}
+	for _, path := range []string{"/api/v1/posts/2", "/api/v1/posts/3", "/api/v1/users/2/posts"} {
+		if rec := serve(router, http.MethodGet, path, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want 404", path, rec.Code)
+		}
+	}
+}
+
+func TestPostListAuthorStrategies(t *testing.T) {
+	router, store, _ := newTestServer(t)
+	for _, uid := range []int64{1, 2, 3, 1, 2} {
+		if _, err := store.CreatePost(uid, "Title", "Body"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query   string
+		queries int
+		authors bool
+	}{
+		{query: "", queries: 2},
+		{query: "?expand=author", queries: 2, authors: true},
+		{query: "?expand=author&strategy=join", queries: 2, authors: true},
+		{query: "?expand=author&strategy=scan", queries: 2, authors: true},
+		{query: "?expand=author&strategy=nplus1", queries: 2 + 5, authors: true},
+	}
+	var want []Post
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := serve(router, http.MethodGet, "/api/v1/posts"+tt.query, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d (body %s)", rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("X-Query-Count"); got != strconv.Itoa(tt.queries) {
+				t.Errorf("X-Query-Count %s, want %d", got, tt.queries)
+			}
+			var posts []Post
+			if err := json.Unmarshal(rec.Body.Bytes(), &posts); err != nil {
+				t.Fatal(err)
+			}
+			if len(posts) != 5 {
+				t.Fatalf("%d posts, want 5", len(posts))
+			}
+			for _, p := range posts {
+				if (p.Author != nil) != tt.authors {
+					t.Fatalf("post %d author %+v, want embedded=%v", p.ID, p.Author, tt.authors)
+				}
+				if p.Author != nil && p.Author.ID != p.UserID {
+					t.Errorf("post %d by user %d embeds user %d", p.ID, p.UserID, p.Author.ID)
+				}
+			}
+			// Every strategy must return the same listing.
+			if tt.authors {
+				if want == nil {
+					want = posts
+				} else if !reflect.DeepEqual(posts, want) {
+					t.Errorf("listing differs from the join strategy")
+				}
+			}
+		})
+	}
+
+	rec := serve(router, http.MethodGet, "/api/v1/posts?expand=comments&strategy=fast", "")
+	if rec.Code != http.StatusBadRequest || errorCode(rec) != codeBadRequest {
+		t.Errorf("bad options: status %d, code %q", rec.Code, errorCode(rec))
+	}
+}
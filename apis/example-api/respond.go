@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"dinky-shared/respond"
+)
+
+// Error codes returned in the "code" field of the respond.Envelope error body.
+const (
+	codeBadRequest       = "bad_request"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeInvalidJSON      = "invalid_json"
+	codeValidationFailed = "validation_failed"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeConflict         = "conflict"
+	codeGone             = "gone"
+	codePayloadTooLarge  = "payload_too_large"
+	codeInternal         = "internal_error"
+	codeInjectedFault    = "injected_fault"
+)
+
+// maxJSONBodyBytes caps JSON request bodies; the largest valid payload, a
+// post with a full-length body, is well under it.
+const maxJSONBodyBytes = 1 << 20
+
+// decodeJSON reads exactly one JSON value into v, rejecting oversized bodies,
+// fields v does not declare and anything after the value. On failure it has
+// already written the error response.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJSONBodyBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		if dec.Decode(&struct{}{}) == io.EOF {
+			return true
+		}
+		respond.Error(w, http.StatusBadRequest, codeInvalidJSON, "request body must hold a single JSON value")
+		return false
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, io.EOF):
+		respond.Error(w, http.StatusBadRequest, codeInvalidJSON, "request body is empty")
+	case errors.As(err, &maxErr):
+		respond.Error(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge,
+			fmt.Sprintf("request body must be at most %d bytes", maxErr.Limit))
+	case errors.As(err, &typeErr):
+		respond.ErrorDetails(w, http.StatusBadRequest, codeInvalidJSON, "request body has the wrong type for a field",
+			[]respond.FieldError{{Field: typeErr.Field, Message: fmt.Sprintf("must be a %s", typeErr.Type)}})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for this case.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		respond.ErrorDetails(w, http.StatusBadRequest, codeInvalidJSON, "request body has an unknown field",
+			[]respond.FieldError{{Field: field, Message: "is not a known field"}})
+	default:
+		respond.Error(w, http.StatusBadRequest, codeInvalidJSON, "request body is not valid JSON")
+	}
+	return false
+}
+
+// writeValidationError responds 422 with one detail per rejected field.
+func writeValidationError(w http.ResponseWriter, details []respond.FieldError) {
+	respond.ErrorDetails(w, http.StatusUnprocessableEntity, codeValidationFailed, "request validation failed", details)
+}
+
+func notFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond.Error(w, http.StatusNotFound, codeNotFound, "route not found")
+	})
+}
+
+func methodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond.Error(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dinky-shared/respond"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		ok     bool
+		status int
+		field  string
+	}{
+		{name: "valid", body: `{"name":"Eve","email":"eve@example.com"}`, ok: true},
+		{name: "trailing newline", body: "{\"name\":\"Eve\"}\n", ok: true},
+		{name: "empty", body: ``, status: http.StatusBadRequest},
+		{name: "malformed", body: `{"name":`, status: http.StatusBadRequest},
+		{name: "trailing garbage", body: `{"name":"Eve"} garbage`, status: http.StatusBadRequest},
+		{name: "second value", body: `{"name":"Eve"}{}`, status: http.StatusBadRequest},
+		{name: "unknown field", body: `{"name":"Eve","id":4}`, status: http.StatusBadRequest, field: "id"},
+		{name: "wrong type", body: `{"name":5}`, status: http.StatusBadRequest, field: "name"},
+		{name: "too large", body: `{"name":"` + strings.Repeat("a", maxJSONBodyBytes) + `"}`, status: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(tt.body))
+			var req userRequest
+
+			if got := decodeJSON(rec, r, &req); got != tt.ok {
+				t.Fatalf("decodeJSON = %v, want %v (body %s)", got, tt.ok, rec.Body)
+			}
+			if tt.ok {
+				return
+			}
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d", rec.Code, tt.status)
+			}
+			var env respond.Envelope
+			if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+				t.Fatalf("decode error body: %v", err)
+			}
+			if tt.field != "" && (len(env.Error.Details) != 1 || env.Error.Details[0].Field != tt.field) {
+				t.Errorf("details %+v, want one for field %q", env.Error.Details, tt.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"dinky-shared/health"
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// apiPrefix is where the current API version is mounted.
+const apiPrefix = "/api/v1"
+
+var deprecatedRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "deprecated_requests_total",
+	Help: "Requests served on deprecated unversioned routes, by route and outcome (served/gone).",
+}, []string{"route", "outcome"})
+
+// apiDeps is everything the resource routes need. The same values back both
+// the /api/v1 routes and the legacy ones, so the two share state.
+type apiDeps struct {
+	store        *Store
+	files        *fileService
+	wsHub        *wsHub
+	shuttingDown <-chan struct{}
+}
+
+// newRouter wires every HTTP route: infrastructure and admin endpoints at the
+// root, the resource routes under /api/v1, and the same resource routes again
+// at their deprecated unversioned paths.
+func newRouter(cfg Config, d apiDeps, backups *backupService) *mux.Router {
+	router := mux.NewRouter()
+	faults := newFaultInjector(cfg.WriteTimeout.Duration)
+	router.Use(metricsMiddleware, requestLogMiddleware, faults.middleware)
+	// mux only runs Use middleware on matched routes, so 404s and 405s are
+	// counted and logged by wrapping their handlers directly.
+	router.NotFoundHandler = metricsMiddleware(requestLogMiddleware(notFoundHandler()))
+	router.MethodNotAllowedHandler = metricsMiddleware(requestLogMiddleware(methodNotAllowedHandler()))
+
+	// Prometheus metrics
+	router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+
+	// API documentation
+	router.HandleFunc("/openapi.json", openAPIHandler).Methods("GET")
+	router.HandleFunc("/docs", swaggerUIHandler).Methods("GET")
+	router.PathPrefix("/docs/swagger-ui/").Handler(swaggerAssets).Methods("GET")
+
+	// Liveness and readiness; /health is kept as an alias of /healthz
+	router.HandleFunc("/healthz", health.Healthz("example-api")).Methods("GET")
+	router.HandleFunc("/health", health.Healthz("example-api")).Methods("GET")
+	router.HandleFunc("/readyz", newReadinessChecks(d.store, cfg.UploadDir, d.shuttingDown).Readyz).Methods("GET")
+
+	// Admin endpoints, off unless ADMIN_TOKEN is set
+	admin := adminAuth(cfg.AdminToken)
+
+	// Fault injection baseline
+	router.Handle("/admin/faults", admin(getFaults(faults))).Methods("GET")
+	router.Handle("/admin/faults", admin(putFaults(faults))).Methods("PUT")
+
+	// Deterministic test data
+	router.Handle("/admin/seed", admin(seedData(d.store, cfg.TransferTimeout.Duration))).Methods("POST")
+	router.Handle("/admin/reset", admin(resetData(d.store))).Methods("POST")
+
+	// Database backups
+	router.Handle("/admin/backups", admin(backups.handleList)).Methods("GET")
+	router.Handle("/admin/backups", admin(backups.handleCreate)).Methods("POST")
+	router.Handle("/admin/backups/{name}/restore", admin(backups.handleRestore)).Methods("POST")
+
+	// Versioned API
+	registerAPIRoutes(apiRoutes{router: router, prefix: apiPrefix}, d)
+
+	// Root endpoint
+	router.HandleFunc("/", rootHandler).Methods("GET")
+
+	// Unversioned paths predate /api/v1; they keep working with deprecation
+	// headers until the sunset date and answer 410 Gone afterwards.
+	registerAPIRoutes(apiRoutes{router: router, wrap: deprecationMiddleware(cfg.LegacySunset.Time)}, d)
+
+	return router
+}
+
+// apiRoutes registers resource routes on the root router under prefix, with
+// every handler wrapped in wrap when it is set.
+//
+// The routes deliberately do not live on a PathPrefix subrouter: mux gives
+// each subrouter route the prefix as an extra matcher, and that matcher
+// succeeding on a later route clears an earlier method mismatch, turning
+// every 405 under the prefix into a 404.
+type apiRoutes struct {
+	router *mux.Router
+	prefix string
+	wrap   mux.MiddlewareFunc
+}
+
+func (a apiRoutes) handle(path string, h http.HandlerFunc, methods ...string) {
+	var handler http.Handler = h
+	if a.wrap != nil {
+		handler = a.wrap(handler)
+	}
+	a.router.Handle(a.prefix+path, handler).Methods(methods...)
+}
+
+// registerAPIRoutes adds the resource routes to r. It is called once for
+// /api/v1 and once for the deprecated unversioned paths.
+func registerAPIRoutes(r apiRoutes, d apiDeps) {
+	// Hello endpoint
+	r.handle("/hello", helloHandler, "GET")
+
+	// Users endpoints (SQLite-backed)
+	r.handle("/users", listUsers(d.store), "GET")
+	r.handle("/users", createUser(d.store), "POST")
+	r.handle("/users/{id}", getUser(d.store), "GET")
+	r.handle("/users/{id}", replaceUser(d.store), "PUT")
+	r.handle("/users/{id}", patchUser(d.store), "PATCH")
+	r.handle("/users/{id}", deleteUser(d.store), "DELETE")
+	r.handle("/users/{id}/posts", listUserPosts(d.store), "GET")
+	r.handle("/users/{id}/posts", createUserPost(d.store), "POST")
+
+	// Posts endpoints, each belonging to a user
+	r.handle("/posts", listPosts(d.store), "GET")
+	r.handle("/posts", createPost(d.store), "POST")
+	r.handle("/posts/{id}", getPost(d.store), "GET")
+	r.handle("/posts/{id}", replacePost(d.store), "PUT")
+	r.handle("/posts/{id}", patchPost(d.store), "PATCH")
+	r.handle("/posts/{id}", deletePost(d.store), "DELETE")
+
+	// File upload and download
+	r.handle("/files", d.files.list, "GET")
+	r.handle("/files", d.files.upload, "POST")
+	r.handle("/files/{id}", d.files.download, "GET", "HEAD")
+	r.handle("/files/{id}/meta", d.files.metadata, "GET")
+	r.handle("/files/{id}", d.files.remove, "DELETE")
+
+	// WebSocket echo/broadcast channel
+	r.handle("/ws", wsHandler(d.wsHub), "GET")
+
+	// Server-Sent Events stream
+	r.handle("/events", eventsHandler(d.shuttingDown), "GET")
+}
+
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{
+		"message":   "Welcome to Example API",
+		"endpoints": []string{"/healthz", "/readyz", "/metrics", "/openapi.json", "/docs", "/admin/faults", "/admin/seed", "/admin/reset", "/admin/backups", "/api/v1/hello", "/api/v1/users", "/api/v1/users/{id}", "/api/v1/users/{id}/posts", "/api/v1/posts", "/api/v1/posts/{id}", "/api/v1/files", "/api/v1/files/{id}", "/api/v1/ws", "/api/v1/events"},
+		"version":   "1.0.0",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func helloHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{
+		"message": "Hello from Example API!",
+		"time":    time.Now().Format(time.RFC3339),
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// deprecationMiddleware marks responses on legacy routes with the
+// Deprecation, Sunset and successor Link headers (RFC 8594). Once sunset has
+// passed the routes answer 410 Gone instead of serving the request.
+func deprecationMiddleware(sunset time.Time) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			successor := apiPrefix + r.URL.Path
+			route := routeLabel(r)
+
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+
+			if !time.Now().Before(sunset) {
+				deprecatedRequestsTotal.WithLabelValues(route, "gone").Inc()
+				respond.Error(w, http.StatusGone, codeGone, "this route was retired; use "+successor)
+				return
+			}
+			deprecatedRequestsTotal.WithLabelValues(route, "served").Inc()
+			logging.Debugf("deprecated route %s %s, successor %s", r.Method, r.URL.Path, successor)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dinky-shared/respond"
+)
+
+// newTestServer builds the full router over a fresh store in a temporary
+// directory.
+func newTestServer(t *testing.T) (http.Handler, *Store, *backupService) {
+	t.Helper()
+	return newTestServerWith(t, nil)
+}
+
+// newTestServerWith is newTestServer with the default config adjusted by
+// configure first.
+func newTestServerWith(t *testing.T, configure func(*Config)) (http.Handler, *Store, *backupService) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := defaultConfig()
+	cfg.DBPath = filepath.Join(dir, "example-api.db")
+	cfg.UploadDir = filepath.Join(dir, "uploads")
+	cfg.BackupDir = filepath.Join(dir, "backups")
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	store, err := OpenStore(cfg.DBPath)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	files, err := newFileService(store, cfg)
+	if err != nil {
+		t.Fatalf("newFileService: %v", err)
+	}
+	backups, err := newBackupService(store, cfg)
+	if err != nil {
+		t.Fatalf("newBackupService: %v", err)
+	}
+
+	deps := apiDeps{store: store, files: files, wsHub: newWSHub(), shuttingDown: make(chan struct{})}
+	return newRouter(cfg, deps, backups), store, backups
+}
+
+// serve sends one request through h. A non-empty body is sent as JSON.
+func serve(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+// errorCode returns the code of the error envelope in rec, or "" when the
+// body is not one.
+func errorCode(rec *httptest.ResponseRecorder) string {
+	var env respond.Envelope
+	if json.Unmarshal(rec.Body.Bytes(), &env) != nil {
+		return ""
+	}
+	return env.Error.Code
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	router, _, _ := newTestServer(t)
+
+	tests := []struct {
+		method, path string
+	}{
+		{http.MethodPut, "/api/v1/users"},
+		{http.MethodPut, "/users"},
+		{http.MethodPost, "/api/v1/posts/1"},
+		{http.MethodPost, "/posts/1"},
+		{http.MethodDelete, "/api/v1/users/1/posts"},
+		{http.MethodPost, "/api/v1/hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status %d, want 405 (body %s)", rec.Code, rec.Body)
+			}
+			var env respond.Envelope
+			if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if env.Error.Code != codeMethodNotAllowed {
+				t.Errorf("code %q, want %q", env.Error.Code, codeMethodNotAllowed)
+			}
+		})
+	}
+}
+
+func TestUnknownRouteNotFound(t *testing.T) {
+	router, _, _ := newTestServer(t)
+
+	for _, path := range []string{"/api/v1/nope", "/nope"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want 404", path, rec.Code)
+		}
+	}
+}
+
+func TestLegacyRouteHeaders(t *testing.T) {
+	router, _, _ := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation %q, want true", got)
+	}
+	if got, want := rec.Header().Get("Link"), `</api/v1/users>; rel="successor-version"`; got != want {
+		t.Errorf("Link %q, want %q", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil))
+	if got := rec.Header().Get("Deprecation"); got != "" {
+		t.Errorf("versioned route has Deprecation %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+)
+
+const (
+	maxSeedUsers = 100000
+	maxSeedPosts = 1000000
+)
+
+var (
+	seedFirstNames = []string{"Alice", "Bob", "Charlie", "Dana", "Eve", "Frank", "Grace", "Heidi", "Ivan", "Judy", "Mallory", "Niaj", "Olivia", "Peggy", "Rupert", "Sybil", "Trent", "Victor", "Walter", "Zoe"}
+	seedLastNames  = []string{"Smith", "Jones", "Garcia", "Miller", "Davis", "Lopez", "Wilson", "Moore", "Taylor", "Clark", "Lewis", "Walker", "Young", "King", "Scott"}
+	seedWords      = []string{"server", "docker", "proxy", "metrics", "latency", "cache", "deploy", "backup", "tunnel", "query", "index", "trace", "alert", "dashboard", "volume", "network", "config", "release", "monitor", "queue"}
+
+	// seedEpoch anchors generated timestamps so the same seed produces
+	// byte-identical data on every run.
+	seedEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// seedDataset generates users and posts from a fixed random source. Posts
+// are spread unevenly across users so some users have many posts.
+func seedDataset(users, posts int, seed int64) ([]User, []Post) {
+	rng := rand.New(rand.NewSource(seed))
+
+	us := make([]User, users)
+	for i := range us {
+		first := seedFirstNames[rng.Intn(len(seedFirstNames))]
+		last := seedLastNames[rng.Intn(len(seedLastNames))]
+		created := seedEpoch.Add(time.Duration(i) * time.Minute)
+		us[i] = User{
+			Name:      first + " " + last,
+			Email:     fmt.Sprintf("%s.%s.%d@example.com", strings.ToLower(first), strings.ToLower(last), i+1),
+			CreatedAt: created,
+			UpdatedAt: created,
+		}
+	}
+
+	ps := make([]Post, posts)
+	for i := range ps {
+		// Squaring a uniform value skews authorship toward low user IDs.
+		f := rng.Float64()
+		author := int64(f*f*float64(users)) + 1
+		created := seedEpoch.Add(time.Duration(i) * time.Second)
+		ps[i] = Post{
+			UserID:    author,
+			Title:     seedSentence(rng, 3+rng.Intn(5)),
+			Body:      seedSentence(rng, 20+rng.Intn(80)),
+			CreatedAt: created,
+			UpdatedAt: created,
+		}
+	}
+	return us, ps
+}
+
+func seedSentence(rng *rand.Rand, words int) string {
+	parts := make([]string, words)
+	for i := range parts {
+		parts[i] = seedWords[rng.Intn(len(seedWords))]
+	}
+	s := strings.Join(parts, " ")
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// seedData serves POST /admin/seed?users=N&posts=M&seed=S, replacing all
+// users and posts with a generated dataset of exactly that size. The same
+// parameters always produce the same data. Large datasets take a while to
+// insert, so the request gets the transfer timeout instead of the write
+// timeout.
+func seedData(store *Store, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		users, posts, seed, errs := parseSeedParams(r)
+		if len(errs) > 0 {
+			respond.ErrorDetails(w, http.StatusBadRequest, codeBadRequest, "invalid query parameters", errs)
+			return
+		}
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+			logging.Debugf("cannot extend write deadline: %v", err)
+		}
+
+		start := time.Now()
+		us, ps := seedDataset(users, posts, seed)
+		if err := store.ReplaceData(us, ps); err != nil {
+			logging.Errorf("seed failed: %v", err)
+			respond.Error(w, http.StatusInternalServerError, codeInternal, "seed failed")
+			return
+		}
+		took := time.Since(start)
+		logging.Infof("seeded %d users and %d posts (seed %d) in %s", users, posts, seed, took)
+		respond.JSON(w, http.StatusOK, map[string]interface{}{
+			"users":       users,
+			"posts":       posts,
+			"seed":        seed,
+			"duration_ms": took.Milliseconds(),
+		})
+	}
+}
+
+// resetData serves POST /admin/reset, removing all users and posts.
+func resetData(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := store.ReplaceData(nil, nil); err != nil {
+			logging.Errorf("reset failed: %v", err)
+			respond.Error(w, http.StatusInternalServerError, codeInternal, "reset failed")
+			return
+		}
+		logging.Infof("store reset")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func parseSeedParams(r *http.Request) (users, posts int, seed int64, errs []respond.FieldError) {
+	q := r.URL.Query()
+	users, posts, seed = 100, 1000, 1
+
+	if v := q.Get("users"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxSeedUsers {
+			errs = append(errs, respond.FieldError{Field: "users", Message: fmt.Sprintf("must be an integer between 0 and %d", maxSeedUsers)})
+		}
+		users = n
+	}
+	if v := q.Get("posts"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxSeedPosts {
+			errs = append(errs, respond.FieldError{Field: "posts", Message: fmt.Sprintf("must be an integer between 0 and %d", maxSeedPosts)})
+		}
+		posts = n
+	}
+	if v := q.Get("seed"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			errs = append(errs, respond.FieldError{Field: "seed", Message: "must be an integer"})
+		}
+		seed = n
+	}
+	if len(errs) == 0 && posts > 0 && users == 0 {
+		errs = append(errs, respond.FieldError{Field: "posts", Message: "requires at least one user"})
+	}
+	return users, posts, seed, errs
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+// dump returns the users and posts listings, which together cover every
+// seeded field.
+func dump(t *testing.T, router http.Handler) (users, posts string) {
+	t.Helper()
+	u := serve(router, http.MethodGet, "/api/v1/users?limit=100", "")
+	p := serve(router, http.MethodGet, "/api/v1/posts?limit=100", "")
+	if u.Code != http.StatusOK || p.Code != http.StatusOK {
+		t.Fatalf("list status %d/%d", u.Code, p.Code)
+	}
+	return u.Body.String(), p.Body.String()
+}
+
+func TestSeedIsDeterministic(t *testing.T) {
+	router, store, _ := newAdminTestServer(t)
+
+	if rec := serveAdmin(router, http.MethodPost, "/admin/seed?users=5&posts=20&seed=7"); rec.Code != http.StatusOK {
+		t.Fatalf("seed status %d (body %s)", rec.Code, rec.Body)
+	}
+	users, posts := dump(t, router)
+
+	// Data written in between must not survive a reseed or shift its IDs.
+	if _, err := store.CreateUser("Dana", "dana@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	serveAdmin(router, http.MethodPost, "/admin/seed?users=5&posts=20&seed=7")
+	again, againPosts := dump(t, router)
+	if again != users || againPosts != posts {
+		t.Error("reseeding with the same parameters produced different data")
+	}
+
+	list, total, err := store.ListUsers(UserQuery{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 || list[0].ID != 1 || list[4].ID != 5 {
+		t.Errorf("got %d users with IDs %d..%d, want 5 with IDs 1..5", total, list[0].ID, list[len(list)-1].ID)
+	}
+	if _, total, _ := store.ListPosts(PostQuery{Limit: 100}); total != 20 {
+		t.Errorf("got %d posts, want 20", total)
+	}
+
+	serveAdmin(router, http.MethodPost, "/admin/seed?users=5&posts=20&seed=8")
+	if other, _ := dump(t, router); other == users {
+		t.Error("a different seed produced the same users")
+	}
+}
+
+func TestResetEmptiesStore(t *testing.T) {
+	router, store, _ := newAdminTestServer(t)
+
+	if rec := serveAdmin(router, http.MethodPost, "/admin/reset"); rec.Code != http.StatusNoContent {
+		t.Fatalf("reset status %d (body %s)", rec.Code, rec.Body)
+	}
+	if _, total, _ := store.ListUsers(UserQuery{Limit: 1}); total != 0 {
+		t.Errorf("%d users left after reset", total)
+	}
+	if _, total, _ := store.ListPosts(PostQuery{Limit: 1}); total != 0 {
+		t.Errorf("%d posts left after reset", total)
+	}
+
+	// IDs restart once the store is refilled.
+	serveAdmin(router, http.MethodPost, "/admin/seed?users=1&posts=0")
+	if _, err := store.GetUser(1); err != nil {
+		t.Errorf("GetUser(1) after reseed: %v", err)
+	}
+}
+
+func TestSeedRejectsBadParams(t *testing.T) {
+	router, _, _ := newAdminTestServer(t)
+
+	for _, query := range []string{
+		"?users=-1",
+		"?users=100001",
+		"?posts=abc",
+		"?seed=1.5",
+		"?users=0&posts=1",
+	} {
+		rec := serveAdmin(router, http.MethodPost, "/admin/seed"+query)
+		if rec.Code != http.StatusBadRequest || errorCode(rec) != codeBadRequest {
+			t.Errorf("%s: status %d code %q, want 400 %s", query, rec.Code, errorCode(rec), codeBadRequest)
+		}
+	}
+}
@@ -0,0 +1,579 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	errNotFound    = errors.New("not found")
+	errEmailTaken  = errors.New("email already in use")
+	errUnknownUser = errors.New("user does not exist")
+)
+
+// User is a row in the users table.
+type User struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// migrations are applied in order and tracked in schema_migrations, so only
+// ever append to this list.
+var migrations = []string{
+	`CREATE TABLE users (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		name       TEXT NOT NULL,
+		email      TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`,
+	`INSERT INTO users (name, email, created_at, updated_at) VALUES
+		('Alice', 'alice@example.com', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+		('Bob', 'bob@example.com', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+		('Charlie', 'charlie@example.com', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+	`CREATE TABLE files (
+		id           TEXT PRIMARY KEY,
+		name         TEXT NOT NULL,
+		size         INTEGER NOT NULL,
+		content_type TEXT NOT NULL,
+		sha256       TEXT NOT NULL,
+		created_at   TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE posts (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		title      TEXT NOT NULL,
+		body       TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX posts_user_id ON posts (user_id)`,
+	// Emails are filtered and sorted case-insensitively, so uniqueness is
+	// too. A database already holding two case variants of one address
+	// fails this migration until one of them is changed.
+	`CREATE UNIQUE INDEX users_email_nocase ON users (email COLLATE NOCASE)`,
+}
+
+// Store wraps the SQLite database backing the API.
+type Store struct {
+	db *sql.DB
+}
+
+// OpenStore opens (or creates) the database at path and applies any pending
+// migrations.
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	// SQLite only allows one writer; a single connection avoids SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	current, err := s.SchemaVersion(context.Background())
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+
+	for i := current; i < len(migrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, i+1, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("record migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		fmt.Printf("📦 Applied migration %d\n", i+1)
+	}
+	return nil
+}
+
+// SchemaVersion returns the number of the last applied migration.
+func (s *Store) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// Ping checks that the database file can still be reached.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// UserQuery filters, sorts and pages a user listing.
+type UserQuery struct {
+	Name   string // case-insensitive substring match
+	Email  string // case-insensitive substring match
+	Sort   string // column name, optionally prefixed with "-" for descending
+	Limit  int
+	Offset int
+}
+
+// sortColumns whitelists the columns ListUsers may order by, since ORDER BY
+// cannot take a bound parameter.
+var sortColumns = map[string]string{
+	"id":         "id",
+	"name":       "name COLLATE NOCASE",
+	"email":      "email COLLATE NOCASE",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// ListUsers returns one page of users matching q together with the total
+// number of matching users.
+func (s *Store) ListUsers(q UserQuery) ([]User, int, error) {
+	where := ` WHERE name LIKE ? ESCAPE '\' AND email LIKE ? ESCAPE '\'`
+	args := []interface{}{likePattern(q.Name), likePattern(q.Email)}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	order := "id"
+	if q.Sort != "" {
+		column, desc := strings.TrimPrefix(q.Sort, "-"), strings.HasPrefix(q.Sort, "-")
+		order = sortColumns[column]
+		if desc {
+			order += " DESC"
+		}
+		order += ", id"
+	}
+
+	rows, err := s.db.Query(`SELECT id, name, email, created_at, updated_at FROM users`+where+
+		` ORDER BY `+order+` LIMIT ? OFFSET ?`, append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
+
+// likePattern turns a substring filter into a LIKE pattern, escaping the
+// wildcard characters so they match literally.
+func likePattern(substr string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(substr) + "%"
+}
+
+// GetUser returns the user with the given ID or errNotFound.
+func (s *Store) GetUser(id int64) (User, error) {
+	var u User
+	err := s.db.QueryRow(`SELECT id, name, email, created_at, updated_at FROM users WHERE id = ?`, id).
+		Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt, &u.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, errNotFound
+	}
+	return u, err
+}
+
+// CreateUser inserts a new user. It returns errEmailTaken if the email is
+// already registered.
+func (s *Store) CreateUser(name, email string) (User, error) {
+	now := time.Now().UTC()
+	res, err := s.db.Exec(`INSERT INTO users (name, email, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+		name, email, now, now)
+	if err != nil {
+		return User{}, translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return User{}, err
+	}
+	return User{ID: id, Name: name, Email: email, CreatedAt: now, UpdatedAt: now}, nil
+}
+
+// UpdateUser overwrites the name and email of an existing user.
+func (s *Store) UpdateUser(id int64, name, email string) (User, error) {
+	res, err := s.db.Exec(`UPDATE users SET name = ?, email = ?, updated_at = ? WHERE id = ?`,
+		name, email, time.Now().UTC(), id)
+	if err != nil {
+		return User{}, translateError(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return User{}, errNotFound
+	}
+	return s.GetUser(id)
+}
+
+// DeleteUser removes a user by ID. Their posts go with them through the
+// ON DELETE CASCADE foreign key.
+func (s *Store) DeleteUser(id int64) error {
+	res, err := s.db.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errNotFound
+	}
+	return nil
+}
+
+// Post is a row in the posts table. Author is only filled in when a listing
+// asks for it.
+type Post struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Author    *User     `json:"author,omitempty"`
+}
+
+// PostQuery pages a post listing, optionally restricted to one user.
+type PostQuery struct {
+	UserID int64 // 0 means all users
+	Limit  int
+	Offset int
+}
+
+const postColumns = `p.id, p.user_id, p.title, p.body, p.created_at, p.updated_at`
+
+func (q PostQuery) where() (string, []interface{}) {
+	if q.UserID == 0 {
+		return "", nil
+	}
+	return ` WHERE p.user_id = ?`, []interface{}{q.UserID}
+}
+
+func (s *Store) countPosts(q PostQuery) (int, error) {
+	where, args := q.where()
+	var total int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM posts p`+where, args...).Scan(&total)
+	return total, err
+}
+
+// ListPosts returns one page of posts matching q together with the total
+// number of matching posts. Authors are not loaded.
+func (s *Store) ListPosts(q PostQuery) ([]Post, int, error) {
+	total, err := s.countPosts(q)
+	if err != nil {
+		return nil, 0, err
+	}
+	where, args := q.where()
+	rows, err := s.db.Query(`SELECT `+postColumns+` FROM posts p`+where+
+		` ORDER BY p.id LIMIT ? OFFSET ?`, append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Body, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, total, rows.Err()
+}
+
+// ListPostsWithAuthors is ListPosts with each author loaded in the same
+// query. With indexed false the join condition is wrapped in expressions so
+// SQLite can use neither the primary key nor posts_user_id and falls back to
+// a nested full scan; that is deliberately slow, for demonstrating a bad
+// query plan.
+func (s *Store) ListPostsWithAuthors(q PostQuery, indexed bool) ([]Post, int, error) {
+	total, err := s.countPosts(q)
+	if err != nil {
+		return nil, 0, err
+	}
+	on := `u.id = p.user_id`
+	if !indexed {
+		on = `u.id + 0 = p.user_id + 0`
+	}
+	where, args := q.where()
+	rows, err := s.db.Query(`SELECT `+postColumns+`, u.id, u.name, u.email, u.created_at, u.updated_at
+		FROM posts p JOIN users u ON `+on+where+
+		` ORDER BY p.id LIMIT ? OFFSET ?`, append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		var u User
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Body, &p.CreatedAt, &p.UpdatedAt,
+			&u.ID, &u.Name, &u.Email, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		p.Author = &u
+		posts = append(posts, p)
+	}
+	return posts, total, rows.Err()
+}
+
+// GetPost returns the post with the given ID or errNotFound.
+func (s *Store) GetPost(id int64) (Post, error) {
+	var p Post
+	err := s.db.QueryRow(`SELECT `+postColumns+` FROM posts p WHERE p.id = ?`, id).
+		Scan(&p.ID, &p.UserID, &p.Title, &p.Body, &p.CreatedAt, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Post{}, errNotFound
+	}
+	return p, err
+}
+
+// CreatePost inserts a new post. It returns errUnknownUser if userID does not
+// refer to an existing user.
+func (s *Store) CreatePost(userID int64, title, body string) (Post, error) {
+	now := time.Now().UTC()
+	res, err := s.db.Exec(`INSERT INTO posts (user_id, title, body, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		userID, title, body, now, now)
+	if err != nil {
+		return Post{}, translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Post{}, err
+	}
+	return Post{ID: id, UserID: userID, Title: title, Body: body, CreatedAt: now, UpdatedAt: now}, nil
+}
+
+// UpdatePost overwrites the author, title and body of an existing post.
+func (s *Store) UpdatePost(id, userID int64, title, body string) (Post, error) {
+	res, err := s.db.Exec(`UPDATE posts SET user_id = ?, title = ?, body = ?, updated_at = ? WHERE id = ?`,
+		userID, title, body, time.Now().UTC(), id)
+	if err != nil {
+		return Post{}, translateError(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return Post{}, errNotFound
+	}
+	return s.GetPost(id)
+}
+
+// DeletePost removes a post by ID.
+func (s *Store) DeletePost(id int64) error {
+	res, err := s.db.Exec(`DELETE FROM posts WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errNotFound
+	}
+	return nil
+}
+
+// FileMeta describes an uploaded file; the content lives on disk.
+type FileMeta struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateFile records the metadata of a stored upload.
+func (s *Store) CreateFile(f FileMeta) error {
+	_, err := s.db.Exec(`INSERT INTO files (id, name, size, content_type, sha256, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		f.ID, f.Name, f.Size, f.ContentType, f.SHA256, f.CreatedAt)
+	return err
+}
+
+// ListFiles returns all file metadata, newest first.
+func (s *Store) ListFiles() ([]FileMeta, error) {
+	rows, err := s.db.Query(`SELECT id, name, size, content_type, sha256, created_at FROM files ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []FileMeta{}
+	for rows.Next() {
+		var f FileMeta
+		if err := rows.Scan(&f.ID, &f.Name, &f.Size, &f.ContentType, &f.SHA256, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// GetFile returns the metadata of one file or errNotFound.
+func (s *Store) GetFile(id string) (FileMeta, error) {
+	var f FileMeta
+	err := s.db.QueryRow(`SELECT id, name, size, content_type, sha256, created_at FROM files WHERE id = ?`, id).
+		Scan(&f.ID, &f.Name, &f.Size, &f.ContentType, &f.SHA256, &f.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return FileMeta{}, errNotFound
+	}
+	return f, err
+}
+
+// DeleteFile removes the metadata of one file.
+func (s *Store) DeleteFile(id string) error {
+	res, err := s.db.Exec(`DELETE FROM files WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errNotFound
+	}
+	return nil
+}
+
+// Backup writes a consistent, compacted copy of the database to path, which
+// must not exist yet.
+func (s *Store) Backup(ctx context.Context, path string) error {
+	_, err := s.db.ExecContext(ctx, `VACUUM INTO ?`, path)
+	return err
+}
+
+// Restore replaces the live database with the contents of the SQLite file at
+// path using SQLite's online backup API, then applies any migrations the
+// snapshot predates.
+func (s *Store) Restore(ctx context.Context, path string) error {
+	src, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+	dstConn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = dstConn.Raw(func(dst interface{}) error {
+		return srcConn.Raw(func(src interface{}) error {
+			b, err := dst.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			if _, err := b.Step(-1); err != nil {
+				b.Finish()
+				return err
+			}
+			return b.Finish()
+		})
+	})
+	// Release the single connection before migrate needs it.
+	dstConn.Close()
+	if err != nil {
+		return fmt.Errorf("restore %s: %w", path, err)
+	}
+	return s.migrate()
+}
+
+// ReplaceData wipes users and posts and inserts the given rows in a single
+// transaction. ID sequences restart at 1, so rows get the same IDs on every
+// run; the IDs in users and posts are ignored and posts refer to users by
+// their position (1-based) in users. Files are left alone.
+func (s *Store) ReplaceData(users []User, posts []Post) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []string{
+		`DELETE FROM posts`,
+		`DELETE FROM users`,
+		`DELETE FROM sqlite_sequence WHERE name IN ('users', 'posts')`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	insertUser, err := tx.Prepare(`INSERT INTO users (name, email, created_at, updated_at) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertUser.Close()
+	for _, u := range users {
+		if _, err := insertUser.Exec(u.Name, u.Email, u.CreatedAt, u.UpdatedAt); err != nil {
+			return translateError(err)
+		}
+	}
+
+	insertPost, err := tx.Prepare(`INSERT INTO posts (user_id, title, body, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertPost.Close()
+	for _, p := range posts {
+		if _, err := insertPost.Exec(p.UserID, p.Title, p.Body, p.CreatedAt, p.UpdatedAt); err != nil {
+			return translateError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// translateError maps SQLite constraint violations onto the store's errors.
+func translateError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique:
+		return errEmailTaken
+	case sqlite3.ErrConstraintForeignKey:
+		return errUnknownUser
+	}
+	return err
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+
+	"github.com/gorilla/mux"
+)
+
+type userRequest struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+}
+
+func listUsers(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, errs := parseUserQuery(r.URL.Query())
+		if len(errs) > 0 {
+			respond.ErrorDetails(w, http.StatusBadRequest, codeBadRequest, "invalid query parameters", errs)
+			return
+		}
+		users, total, err := store.ListUsers(q)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		writeCachedList(w, r, users)
+	}
+}
+
+func getUser(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := userID(w, r)
+		if !ok {
+			return
+		}
+		user, err := store.GetUser(id)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeCachedJSON(w, r, user, user.UpdatedAt)
+	}
+}
+
+func createUser(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req userRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		if errs := validateUser(req, false); len(errs) > 0 {
+			writeValidationError(w, errs)
+			return
+		}
+		user, err := store.CreateUser(*req.Name, *req.Email)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.Header().Set("Location", "/api/v1/users/"+strconv.FormatInt(user.ID, 10))
+		respond.JSON(w, http.StatusCreated, user)
+	}
+}
+
+// replaceUser handles PUT, which requires the full representation.
+func replaceUser(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := userID(w, r)
+		if !ok {
+			return
+		}
+		var req userRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		if errs := validateUser(req, false); len(errs) > 0 {
+			writeValidationError(w, errs)
+			return
+		}
+		user, err := store.UpdateUser(id, *req.Name, *req.Email)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		respond.JSON(w, http.StatusOK, user)
+	}
+}
+
+// patchUser handles PATCH, updating only the fields present in the body.
+func patchUser(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := userID(w, r)
+		if !ok {
+			return
+		}
+		var req userRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		if errs := validateUser(req, true); len(errs) > 0 {
+			writeValidationError(w, errs)
+			return
+		}
+		user, err := store.GetUser(id)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if req.Name != nil {
+			user.Name = *req.Name
+		}
+		if req.Email != nil {
+			user.Email = *req.Email
+		}
+		user, err = store.UpdateUser(id, user.Name, user.Email)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		respond.JSON(w, http.StatusOK, user)
+	}
+}
+
+func deleteUser(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := userID(w, r)
+		if !ok {
+			return
+		}
+		if err := store.DeleteUser(id); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func userID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id < 1 {
+		respond.Error(w, http.StatusBadRequest, codeBadRequest, "user id must be a positive integer")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errNotFound):
+		respond.Error(w, http.StatusNotFound, codeNotFound, "user not found")
+	case errors.Is(err, errEmailTaken):
+		respond.ErrorDetails(w, http.StatusConflict, codeConflict, err.Error(),
+			[]respond.FieldError{{Field: "email", Message: "is already in use"}})
+	default:
+		logging.Errorf("store error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, codeInternal, "internal error")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCreateUserEmailIsCaseInsensitive(t *testing.T) {
+	router, _, _ := newTestServer(t)
+
+	tests := []struct {
+		email  string
+		status int
+	}{
+		{"dana@example.com", http.StatusCreated},
+		{"Dana@Example.com", http.StatusConflict},
+		{"ALICE@EXAMPLE.COM", http.StatusConflict}, // seeded as alice@example.com
+		{"dana.b@example.com", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		rec := serve(router, http.MethodPost, "/api/v1/users", `{"name":"Dana","email":"`+tt.email+`"}`)
+		if rec.Code != tt.status {
+			t.Errorf("create %s: status %d, want %d (body %s)", tt.email, rec.Code, tt.status, rec.Body)
+		}
+		if tt.status == http.StatusConflict && errorCode(rec) != codeConflict {
+			t.Errorf("create %s: code %q, want %q", tt.email, errorCode(rec), codeConflict)
+		}
+	}
+
+	rec := serve(router, http.MethodPatch, "/api/v1/users/2", `{"email":"Alice@example.com"}`)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("rename to a case variant: status %d, want 409", rec.Code)
+	}
+}
+
+func TestUserCRUD(t *testing.T) {
+	router, _, _ := newTestServer(t)
+
+	// Steps run in order against one store seeded with users 1-3.
+	steps := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{method: "POST", path: "/api/v1/users", body: `{"name":"Dana","email":"dana@example.com"}`, status: http.StatusCreated},
+		{method: "GET", path: "/api/v1/users/4", status: http.StatusOK},
+		{method: "POST", path: "/api/v1/users", body: `{"name":"Dana","email":"dana@example.com"}`, status: http.StatusConflict, code: codeConflict},
+		{method: "POST", path: "/api/v1/users", body: `{"name":""}`, status: http.StatusUnprocessableEntity, code: codeValidationFailed},
+		{method: "PUT", path: "/api/v1/users/4", body: `{"name":"Dana B","email":"bob@example.com"}`, status: http.StatusConflict, code: codeConflict},
+		{method: "PUT", path: "/api/v1/users/4", body: `{"name":"Dana B","email":"dana.b@example.com"}`, status: http.StatusOK},
+		{method: "PUT", path: "/api/v1/users/99", body: `{"name":"Nobody","email":"nobody@example.com"}`, status: http.StatusNotFound, code: codeNotFound},
+		{method: "PATCH", path: "/api/v1/users/4", body: `{"name":"Dana"}`, status: http.StatusOK},
+		{method: "PATCH", path: "/api/v1/users/99", body: `{"name":"Nobody"}`, status: http.StatusNotFound, code: codeNotFound},
+		{method: "GET", path: "/api/v1/users/abc", status: http.StatusBadRequest, code: codeBadRequest},
+		{method: "GET", path: "/api/v1/users/0", status: http.StatusBadRequest, code: codeBadRequest},
+		{method: "DELETE", path: "/api/v1/users/4", status: http.StatusNoContent},
+		{method: "DELETE", path: "/api/v1/users/4", status: http.StatusNotFound, code: codeNotFound},
+		{method: "GET", path: "/api/v1/users/4", status: http.StatusNotFound, code: codeNotFound},
+	}
+	for _, s := range steps {
+		rec := serve(router, s.method, s.path, s.body)
+		if rec.Code != s.status {
+			t.Fatalf("%s %s: status %d, want %d (body %s)", s.method, s.path, rec.Code, s.status, rec.Body)
+		}
+		if s.code != "" && errorCode(rec) != s.code {
+			t.Errorf("%s %s: code %q, want %q", s.method, s.path, errorCode(rec), s.code)
+		}
+	}
+}
+
+func TestListUsersQuery(t *testing.T) {
+	router, _, _ := newTestServer(t)
+
+	tests := []struct {
+		query string
+		names []string
+		total string
+	}{
+		{query: "", names: []string{"Alice", "Bob", "Charlie"}, total: "3"},
+		{query: "?sort=-name", names: []string{"Charlie", "Bob", "Alice"}, total: "3"},
+		{query: "?limit=1&offset=1", names: []string{"Bob"}, total: "3"},
+		{query: "?name=LI", names: []string{"Alice", "Charlie"}, total: "2"},
+		{query: "?email=bob@", names: []string{"Bob"}, total: "1"},
+		{query: "?name=%25", names: []string{}, total: "0"},
+		{query: "?offset=10", names: []string{}, total: "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := serve(router, http.MethodGet, "/api/v1/users"+tt.query, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d (body %s)", rec.Code, rec.Body)
+			}
+			var users []User
+			if err := json.Unmarshal(rec.Body.Bytes(), &users); err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, u := range users {
+				names = append(names, u.Name)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("names %v, want %v", names, tt.names)
+			}
+			if got := rec.Header().Get("X-Total-Count"); got != tt.total {
+				t.Errorf("X-Total-Count %s, want %s", got, tt.total)
+			}
+		})
+	}
+
+	rec := serve(router, http.MethodGet, "/api/v1/users?limit=0&sort=password", "")
+	if rec.Code != http.StatusBadRequest || errorCode(rec) != codeBadRequest {
+		t.Errorf("invalid query: status %d, code %q", rec.Code, errorCode(rec))
+	}
+}
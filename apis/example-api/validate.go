@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"dinky-shared/respond"
+)
+
+const (
+	maxNameLength    = 100
+	maxTitleLength   = 200
+	maxBodyLength    = 10000
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// validateUser checks the fields of a user request. When partial is true
+// (PATCH), absent fields are skipped instead of reported as missing.
+func validateUser(req userRequest, partial bool) []respond.FieldError {
+	var errs []respond.FieldError
+
+	switch {
+	case req.Name == nil:
+		if !partial {
+			errs = append(errs, respond.FieldError{Field: "name", Message: "is required"})
+		}
+	case strings.TrimSpace(*req.Name) == "":
+		errs = append(errs, respond.FieldError{Field: "name", Message: "must not be blank"})
+	case utf8.RuneCountInString(*req.Name) > maxNameLength:
+		errs = append(errs, respond.FieldError{Field: "name", Message: fmt.Sprintf("must be at most %d characters", maxNameLength)})
+	}
+
+	switch {
+	case req.Email == nil:
+		if !partial {
+			errs = append(errs, respond.FieldError{Field: "email", Message: "is required"})
+		}
+	case !validEmail(*req.Email):
+		errs = append(errs, respond.FieldError{Field: "email", Message: "must be a valid email address"})
+	}
+
+	return errs
+}
+
+// validEmail accepts a bare address such as "alice@example.com" and rejects
+// display-name forms like "Alice <alice@example.com>".
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && strings.Contains(email[strings.LastIndex(email, "@"):], ".")
+}
+
+// parseUserQuery reads limit, offset, name, email and sort from the query
+// string of GET /users.
+func parseUserQuery(values url.Values) (UserQuery, []respond.FieldError) {
+	q := UserQuery{
+		Name:  values.Get("name"),
+		Email: values.Get("email"),
+		Sort:  values.Get("sort"),
+		Limit: defaultPageLimit,
+	}
+
+	q.Limit, q.Offset = parsePaging(values)
+	return q, validateUserQuery(q)
+}
+
+// parsePaging reads limit and offset. Unparseable numbers become -1 so
+// validatePaging reports them.
+func parsePaging(values url.Values) (limit, offset int) {
+	limit = defaultPageLimit
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			n = -1
+		}
+		limit = n
+	}
+	if v := values.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			n = -1
+		}
+		offset = n
+	}
+	return limit, offset
+}
+
+func validatePaging(limit, offset int) []respond.FieldError {
+	var errs []respond.FieldError
+	if limit < 1 || limit > maxPageLimit {
+		errs = append(errs, respond.FieldError{Field: "limit", Message: fmt.Sprintf("must be an integer between 1 and %d", maxPageLimit)})
+	}
+	if offset < 0 {
+		errs = append(errs, respond.FieldError{Field: "offset", Message: "must be a non-negative integer"})
+	}
+	return errs
+}
+
+// validateUserQuery checks paging and sort options shared by the REST and
+// gRPC listings.
+func validateUserQuery(q UserQuery) []respond.FieldError {
+	errs := validatePaging(q.Limit, q.Offset)
+	if q.Sort != "" {
+		if _, ok := sortColumns[strings.TrimPrefix(q.Sort, "-")]; !ok {
+			errs = append(errs, respond.FieldError{Field: "sort", Message: "must be one of id, name, email, created_at, updated_at (prefix with - for descending)"})
+		}
+	}
+	return errs
+}
+
+// validatePost checks the fields of a post request. When partial is true
+// (PATCH), absent fields are skipped instead of reported as missing.
+func validatePost(req postRequest, partial bool) []respond.FieldError {
+	var errs []respond.FieldError
+
+	switch {
+	case req.UserID == nil:
+		if !partial {
+			errs = append(errs, respond.FieldError{Field: "user_id", Message: "is required"})
+		}
+	case *req.UserID < 1:
+		errs = append(errs, respond.FieldError{Field: "user_id", Message: "must be a positive integer"})
+	}
+
+	switch {
+	case req.Title == nil:
+		if !partial {
+			errs = append(errs, respond.FieldError{Field: "title", Message: "is required"})
+		}
+	case strings.TrimSpace(*req.Title) == "":
+		errs = append(errs, respond.FieldError{Field: "title", Message: "must not be blank"})
+	case utf8.RuneCountInString(*req.Title) > maxTitleLength:
+		errs = append(errs, respond.FieldError{Field: "title", Message: fmt.Sprintf("must be at most %d characters", maxTitleLength)})
+	}
+
+	switch {
+	case req.Body == nil:
+		if !partial {
+			errs = append(errs, respond.FieldError{Field: "body", Message: "is required"})
+		}
+	case utf8.RuneCountInString(*req.Body) > maxBodyLength:
+		errs = append(errs, respond.FieldError{Field: "body", Message: fmt.Sprintf("must be at most %d characters", maxBodyLength)})
+	}
+
+	return errs
+}
+
+// parsePostQuery reads user_id, limit, offset, expand and strategy from the
+// query string of GET /posts.
+func parsePostQuery(values url.Values) (PostQuery, postListOptions, []respond.FieldError) {
+	var (
+		q    PostQuery
+		opts = postListOptions{Strategy: strategyJoin}
+		errs []respond.FieldError
+	)
+	q.Limit, q.Offset = parsePaging(values)
+	errs = append(errs, validatePaging(q.Limit, q.Offset)...)
+
+	if v := values.Get("user_id"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			errs = append(errs, respond.FieldError{Field: "user_id", Message: "must be a positive integer"})
+		}
+		q.UserID = n
+	}
+
+	switch v := values.Get("expand"); v {
+	case "":
+	case "author":
+		opts.ExpandAuthor = true
+	default:
+		errs = append(errs, respond.FieldError{Field: "expand", Message: "must be author"})
+	}
+
+	if v := values.Get("strategy"); v != "" {
+		switch v {
+		case strategyJoin, strategyNPlusOne, strategyScan:
+			opts.Strategy = v
+		default:
+			errs = append(errs, respond.FieldError{Field: "strategy", Message: "must be one of join, nplus1, scan"})
+		}
+	}
+
+	return q, opts, errs
+}
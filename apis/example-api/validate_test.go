@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dinky-shared/respond"
+)
+
+func fields(errs []respond.FieldError) []string {
+	var out []string
+	for _, e := range errs {
+		out = append(out, e.Field)
+	}
+	return out
+}
+
+func TestValidateUser(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		req     userRequest
+		partial bool
+		want    []string
+	}{
+		{name: "valid", req: userRequest{Name: str("Alice"), Email: str("alice@example.com")}},
+		{name: "missing both", req: userRequest{}, want: []string{"name", "email"}},
+		{name: "patch with nothing", req: userRequest{}, partial: true},
+		{name: "patch with name only", req: userRequest{Name: str("Alice")}, partial: true},
+		{name: "blank name", req: userRequest{Name: str("   "), Email: str("alice@example.com")}, want: []string{"name"}},
+		{name: "blank name on patch", req: userRequest{Name: str("")}, partial: true, want: []string{"name"}},
+		{name: "name at limit", req: userRequest{Name: str(strings.Repeat("é", maxNameLength)), Email: str("alice@example.com")}},
+		{name: "name too long", req: userRequest{Name: str(strings.Repeat("a", maxNameLength+1)), Email: str("alice@example.com")}, want: []string{"name"}},
+		{name: "email without at", req: userRequest{Name: str("Alice"), Email: str("alice.example.com")}, want: []string{"email"}},
+		{name: "email without dot in domain", req: userRequest{Name: str("Alice"), Email: str("alice@localhost")}, want: []string{"email"}},
+		{name: "email with display name", req: userRequest{Name: str("Alice"), Email: str("Alice <alice@example.com>")}, want: []string{"email"}},
+		{name: "bad email on patch", req: userRequest{Email: str("nope")}, partial: true, want: []string{"email"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fields(validateUser(tt.req, tt.partial)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUserQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  UserQuery
+		errs  []string
+	}{
+		{query: "", want: UserQuery{Limit: defaultPageLimit}},
+		{query: "limit=10&offset=20", want: UserQuery{Limit: 10, Offset: 20}},
+		{query: "name=ali&email=EXAMPLE&sort=-created_at", want: UserQuery{Name: "ali", Email: "EXAMPLE", Sort: "-created_at", Limit: defaultPageLimit}},
+		{query: "limit=1000", want: UserQuery{Limit: maxPageLimit}},
+		{query: "limit=0", want: UserQuery{Limit: 0}, errs: []string{"limit"}},
+		{query: "limit=1001", want: UserQuery{Limit: 1001}, errs: []string{"limit"}},
+		{query: "limit=ten&offset=-1", want: UserQuery{Limit: -1, Offset: -1}, errs: []string{"limit", "offset"}},
+		{query: "offset=x", want: UserQuery{Limit: defaultPageLimit, Offset: -1}, errs: []string{"offset"}},
+		{query: "sort=password", want: UserQuery{Sort: "password", Limit: defaultPageLimit}, errs: []string{"sort"}},
+		{query: "sort=--name", want: UserQuery{Sort: "--name", Limit: defaultPageLimit}, errs: []string{"sort"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, errs := parseUserQuery(values)
+			if got != tt.want {
+				t.Errorf("query %+v, want %+v", got, tt.want)
+			}
+			if f := fields(errs); !reflect.DeepEqual(f, tt.errs) {
+				t.Errorf("errors on %v, want %v", f, tt.errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = wsPongWait * 9 / 10
+	wsMaxMessageSize = 64 * 1024
+	wsSendBuffer     = 64
+)
+
+var (
+	wsConnectionsActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "websocket_connections_active",
+		Help: "Open WebSocket connections, by mode.",
+	}, []string{"mode"})
+
+	wsMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_messages_total",
+		Help: "WebSocket messages, by mode and direction (in/out).",
+	}, []string{"mode", "direction"})
+
+	wsDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "websocket_messages_dropped_total",
+		Help: "Broadcast messages dropped because a client was too slow to read them.",
+	})
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	// The endpoint is a test target for proxies, so accept any origin.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsHub fans broadcast messages out to every connected broadcast client.
+type wsHub struct {
+	mu      sync.Mutex
+	clients map[*wsClient]struct{}
+}
+
+func newWSHub() *wsHub {
+	return &wsHub{clients: make(map[*wsClient]struct{})}
+}
+
+func (h *wsHub) add(c *wsClient) {
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+}
+
+func (h *wsHub) remove(c *wsClient) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+}
+
+// broadcast queues msg for every client without blocking; clients whose send
+// buffer is full miss the message.
+func (h *wsHub) broadcast(msg wsMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		select {
+		case c.send <- msg:
+		default:
+			wsDroppedTotal.Inc()
+		}
+	}
+}
+
+type wsMessage struct {
+	kind int
+	data []byte
+}
+
+type wsClient struct {
+	conn *websocket.Conn
+	mode string
+	send chan wsMessage
+}
+
+// wsHandler serves /ws. With ?mode=echo (the default) every message is sent
+// back to its sender; with ?mode=broadcast it is relayed to all broadcast
+// clients, including the sender.
+func wsHandler(hub *wsHub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mode := r.URL.Query().Get("mode")
+		if mode == "" {
+			mode = "echo"
+		}
+		if mode != "echo" && mode != "broadcast" {
+			respond.ErrorDetails(w, http.StatusBadRequest, codeBadRequest, "invalid query parameters",
+				[]respond.FieldError{{Field: "mode", Message: "must be echo or broadcast"}})
+			return
+		}
+
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade has already written an error response.
+			logging.Debugf("websocket upgrade failed: %v", err)
+			return
+		}
+
+		c := &wsClient{conn: conn, mode: mode, send: make(chan wsMessage, wsSendBuffer)}
+		wsConnectionsActive.WithLabelValues(mode).Inc()
+		if mode == "broadcast" {
+			hub.add(c)
+		}
+
+		go c.writePump()
+		c.readPump(hub)
+
+		if mode == "broadcast" {
+			hub.remove(c)
+		}
+		close(c.send)
+		wsConnectionsActive.WithLabelValues(mode).Dec()
+	}
+}
+
+// readPump reads until the connection fails, dispatching each message
+// according to the client's mode.
+func (c *wsClient) readPump(hub *wsHub) {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		kind, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				logging.Debugf("websocket read error: %v", err)
+			}
+			return
+		}
+		wsMessagesTotal.WithLabelValues(c.mode, "in").Inc()
+
+		msg := wsMessage{kind: kind, data: data}
+		if c.mode == "broadcast" {
+			hub.broadcast(msg)
+			continue
+		}
+		select {
+		case c.send <- msg:
+		default:
+			wsDroppedTotal.Inc()
+		}
+	}
+}
+
+// writePump is the connection's only writer; it also keeps the connection
+// alive with pings.
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(msg.kind, msg.data); err != nil {
+				return
+			}
+			wsMessagesTotal.WithLabelValues(c.mode, "out").Inc()
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func dialWS(t *testing.T, srv *httptest.Server, mode string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?mode=" + mode
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", mode, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	return conn
+}
+
+func readText(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	kind, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if kind != websocket.TextMessage {
+		t.Fatalf("message type %d, want text", kind)
+	}
+	return string(data)
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	srv := httptest.NewServer(wsHandler(newWSHub()))
+	defer srv.Close()
+
+	conn := dialWS(t, srv, "echo")
+	for _, msg := range []string{"hello", "again"} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		if got := readText(t, conn); got != msg {
+			t.Errorf("echo %q, want %q", got, msg)
+		}
+	}
+}
+
+func TestWebSocketBroadcast(t *testing.T) {
+	hub := newWSHub()
+	srv := httptest.NewServer(wsHandler(hub))
+	defer srv.Close()
+
+	a, b := dialWS(t, srv, "broadcast"), dialWS(t, srv, "broadcast")
+	echo := dialWS(t, srv, "echo")
+	// The handler joins the hub after the upgrade completes.
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		hub.mu.Lock()
+		n := len(hub.clients)
+		hub.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d clients in the hub, want 2", n)
+		}
+	}
+
+	if err := a.WriteMessage(websocket.TextMessage, []byte("to everyone")); err != nil {
+		t.Fatal(err)
+	}
+	for name, conn := range map[string]*websocket.Conn{"sender": a, "other": b} {
+		if got := readText(t, conn); got != "to everyone" {
+			t.Errorf("%s got %q", name, got)
+		}
+	}
+
+	// Echo clients are not part of the broadcast.
+	if err := echo.WriteMessage(websocket.TextMessage, []byte("just me")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readText(t, echo); got != "just me" {
+		t.Errorf("echo client got %q, want its own message", got)
+	}
+}
+
+func TestWebSocketRejectsUnknownMode(t *testing.T) {
+	rec := serve(wsHandler(newWSHub()), http.MethodGet, "/?mode=shout", "")
+	if rec.Code != http.StatusBadRequest || errorCode(rec) != codeBadRequest {
+		t.Errorf("status %d, code %q; want 400 %s", rec.Code, errorCode(rec), codeBadRequest)
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Env maps environment variable names to setters that parse a value into
+// the matching configuration field.
+type Env map[string]func(string) error
+
+// Load layers configuration over the defaults already in cfg, which must be
+// a pointer to a struct: first the JSON file named by CONFIG_FILE, then every
+// variable in env that is set and non-empty. Unknown keys in the file are
+// errors, so a misspelled setting cannot silently leave its default in
+// place, and a bad variable is reported by name.
+func Load(cfg interface{}, env Env) error {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("read config file: %w", err)
+		}
+		defer f.Close()
+		dec := json.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+
+	// Sorted so the same bad environment always reports the same variable.
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			if err := env[name](v); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// StringVar sets dst to the variable's value.
+func StringVar(dst *string) func(string) error {
+	return func(v string) error {
+		*dst = v
+		return nil
+	}
+}
+
+// IntVar parses the variable as a decimal int.
+func IntVar(dst *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*dst = n
+		return nil
+	}
+}
+
+// Int64Var parses the variable as a decimal int64.
+func Int64Var(dst *int64) func(string) error {
+	return func(v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*dst = n
+		return nil
+	}
+}
+
+// DurationVar parses the variable with time.ParseDuration.
+func DurationVar(dst *Duration) func(string) error {
+	return func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		dst.Duration = d
+		return nil
+	}
+}
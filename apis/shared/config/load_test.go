@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Name    string   `json:"name"`
+	Port    int      `json:"port"`
+	Limit   int64    `json:"limit"`
+	Timeout Duration `json:"timeout"`
+}
+
+func (c *testConfig) env() Env {
+	return Env{
+		"TEST_NAME":    StringVar(&c.Name),
+		"TEST_PORT":    IntVar(&c.Port),
+		"TEST_LIMIT":   Int64Var(&c.Limit),
+		"TEST_TIMEOUT": DurationVar(&c.Timeout),
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeFile(t, `{"name": "file", "port": 9000, "timeout": "5s"}`))
+	t.Setenv("TEST_PORT", "9100")
+	t.Setenv("TEST_LIMIT", "")
+
+	cfg := testConfig{Name: "default", Port: 8080, Limit: 10}
+	if err := Load(&cfg, cfg.env()); err != nil {
+		t.Fatal(err)
+	}
+	want := testConfig{Name: "file", Port: 9100, Limit: 10, Timeout: Duration{5 * time.Second}}
+	if cfg != want {
+		t.Errorf("got %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{name: "misspelled key", file: `{"prot": 9000}`, want: `unknown field "prot"`},
+		{name: "wrong type", file: `{"port": "9000"}`, want: "parse config file"},
+		{name: "missing file", want: "read config file"},
+		{name: "bad int", env: map[string]string{"TEST_PORT": "eighty"}, want: "TEST_PORT:"},
+		{name: "bad duration", env: map[string]string{"TEST_TIMEOUT": "5"}, want: "TEST_TIMEOUT:"},
+		{name: "first bad variable by name", env: map[string]string{"TEST_TIMEOUT": "x", "TEST_LIMIT": "x"}, want: "TEST_LIMIT:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			switch {
+			case tt.file != "":
+				t.Setenv("CONFIG_FILE", writeFile(t, tt.file))
+			case tt.env == nil:
+				t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.json"))
+			default:
+				t.Setenv("CONFIG_FILE", "")
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			var cfg testConfig
+			err := Load(&cfg, cfg.env())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
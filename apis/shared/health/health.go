@@ -0,0 +1,205 @@
+// Package health is the liveness and readiness framework shared by the dinky
+// APIs, so every service publishes the same /healthz and /readyz payloads.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"dinky-shared/logging"
+	"dinky-shared/respond"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultTimeout bounds a check that does not set its own timeout, so a
+// locked database or hung upstream cannot hang the health checker.
+const defaultTimeout = 2 * time.Second
+
+// Check is one named dependency of a service. A check only runs once
+// everything in DependsOn has passed; otherwise it is reported as skipped.
+// Failing critical checks make the service not ready, failing non-critical
+// ones only degrade it.
+type Check struct {
+	Name      string
+	Critical  bool
+	Timeout   time.Duration
+	DependsOn []string
+	Run       func(ctx context.Context) error
+}
+
+// Result is the uniform per-check entry of a /readyz payload.
+type Result struct {
+	Name       string   `json:"name"`
+	Status     string   `json:"status"` // ok, fail or skipped
+	Critical   bool     `json:"critical"`
+	DependsOn  []string `json:"depends_on,omitempty"`
+	DurationMS float64  `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Report is the /readyz payload. Status is ready, degraded (only
+// non-critical checks failed) or not_ready.
+type Report struct {
+	Status    string   `json:"status"`
+	Service   string   `json:"service"`
+	Checks    []Result `json:"checks"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// Checks is an ordered set of checks forming a dependency graph.
+type Checks struct {
+	service  string
+	checks   []Check
+	up       *prometheus.GaugeVec
+	duration *prometheus.HistogramVec
+}
+
+// New creates an empty set of checks for service, registering the
+// health_check_up and health_check_duration_seconds metrics with reg.
+func New(service string, reg prometheus.Registerer) *Checks {
+	return &Checks{
+		service: service,
+		up: register(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "health_check_up",
+			Help: "Result of the last run of each readiness check (1 ok, 0 failed or skipped).",
+		}, []string{"check"})),
+		duration: register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "health_check_duration_seconds",
+			Help:    "Readiness check latency in seconds, by check.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 4, 8),
+		}, []string{"check"})),
+	}
+}
+
+// register adds c to reg, reusing the collector already there when another
+// set of checks registered it first.
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) C {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			return are.ExistingCollector.(C)
+		}
+		panic(err)
+	}
+	return c
+}
+
+// Register adds a check. Dependencies must be registered first, which keeps
+// the graph acyclic.
+func (h *Checks) Register(c Check) {
+	known := map[string]bool{}
+	for _, existing := range h.checks {
+		known[existing.Name] = true
+	}
+	if known[c.Name] {
+		panic("health check " + c.Name + " registered twice")
+	}
+	for _, dep := range c.DependsOn {
+		if !known[dep] {
+			panic(fmt.Sprintf("health check %s depends on unregistered check %s", c.Name, dep))
+		}
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultTimeout
+	}
+	h.checks = append(h.checks, c)
+}
+
+// Run executes all checks concurrently, each waiting only for its own
+// dependencies, and returns the results in registration order.
+func (h *Checks) Run(ctx context.Context) Report {
+	results := make([]Result, len(h.checks))
+	done := make(map[string]chan struct{}, len(h.checks))
+	index := make(map[string]int, len(h.checks))
+	for i, c := range h.checks {
+		done[c.Name] = make(chan struct{})
+		index[c.Name] = i
+	}
+
+	for i, c := range h.checks {
+		go func(i int, c Check) {
+			defer close(done[c.Name])
+			res := Result{Name: c.Name, Critical: c.Critical, DependsOn: c.DependsOn}
+
+			for _, dep := range c.DependsOn {
+				<-done[dep]
+				if results[index[dep]].Status != "ok" {
+					res.Status, res.Error = "skipped", "dependency "+dep+" is not ok"
+				}
+			}
+			if res.Status == "" {
+				cctx, cancel := context.WithTimeout(ctx, c.Timeout)
+				start := time.Now()
+				err := c.Run(cctx)
+				if err == nil && cctx.Err() != nil {
+					err = cctx.Err()
+				}
+				cancel()
+				took := time.Since(start)
+				res.DurationMS = float64(took.Microseconds()) / 1000
+				h.duration.WithLabelValues(c.Name).Observe(took.Seconds())
+				res.Status = "ok"
+				if err != nil {
+					if errors.Is(err, context.DeadlineExceeded) {
+						err = fmt.Errorf("timed out after %s", c.Timeout)
+					}
+					res.Status, res.Error = "fail", err.Error()
+				}
+			}
+
+			up := 0.0
+			if res.Status == "ok" {
+				up = 1
+			}
+			h.up.WithLabelValues(c.Name).Set(up)
+			results[i] = res
+		}(i, c)
+	}
+	for _, ch := range done {
+		<-ch
+	}
+
+	status := "ready"
+	for _, r := range results {
+		if r.Status == "ok" {
+			continue
+		}
+		if r.Critical {
+			status = "not_ready"
+			break
+		}
+		status = "degraded"
+	}
+	return Report{Status: status, Service: h.service, Checks: results, Timestamp: time.Now().Format(time.RFC3339)}
+}
+
+// Readyz serves the report, answering 503 when a critical check fails so
+// Traefik and Docker stop routing traffic here. Degraded stays 200.
+func (h *Checks) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.Run(r.Context())
+	code := http.StatusOK
+	if report.Status == "not_ready" {
+		code = http.StatusServiceUnavailable
+	}
+	if report.Status != "ready" {
+		logging.Warnf("readiness %s: %+v", report.Status, report.Checks)
+	}
+	respond.JSON(w, code, report)
+}
+
+// Healthz returns the liveness handler for service: the process is up and
+// serving HTTP. It never touches dependencies, so a slow database or
+// upstream does not get the container restarted.
+func Healthz(service string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respond.JSON(w, http.StatusOK, map[string]interface{}{
+			"status":    "healthy",
+			"service":   service,
+			"timestamp": time.Now().Format(time.RFC3339),
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func pass(context.Context) error { return nil }
+
+func fail(context.Context) error { return errors.New("boom") }
+
+// checkUp reads the health_check_up gauge of one check from reg.
+func checkUp(t *testing.T, reg *prometheus.Registry, check string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range families {
+		if mf.GetName() != "health_check_up" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "check" && l.GetValue() == check {
+					return m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	t.Fatalf("no health_check_up sample for %s", check)
+	return 0
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		status string
+		want   map[string]string // check name -> result status
+	}{
+		{
+			name: "all ok",
+			checks: []Check{
+				{Name: "db", Critical: true, Run: pass},
+				{Name: "schema", Critical: true, DependsOn: []string{"db"}, Run: pass},
+			},
+			status: "ready",
+			want:   map[string]string{"db": "ok", "schema": "ok"},
+		},
+		{
+			name: "non-critical failure degrades",
+			checks: []Check{
+				{Name: "db", Critical: true, Run: pass},
+				{Name: "cache", Run: fail},
+			},
+			status: "degraded",
+			want:   map[string]string{"db": "ok", "cache": "fail"},
+		},
+		{
+			name: "critical failure is not ready",
+			checks: []Check{
+				{Name: "db", Critical: true, Run: fail},
+				{Name: "cache", Run: fail},
+			},
+			status: "not_ready",
+			want:   map[string]string{"db": "fail", "cache": "fail"},
+		},
+		{
+			name: "dependents of a failure are skipped",
+			checks: []Check{
+				{Name: "db", Run: fail},
+				{Name: "schema", DependsOn: []string{"db"}, Run: pass},
+				{Name: "reports", DependsOn: []string{"schema"}, Run: pass},
+				{Name: "disk", Run: pass},
+			},
+			status: "degraded",
+			want:   map[string]string{"db": "fail", "schema": "skipped", "reports": "skipped", "disk": "ok"},
+		},
+		{
+			name: "skipped critical check is not ready",
+			checks: []Check{
+				{Name: "upstream", Run: fail},
+				{Name: "route", Critical: true, DependsOn: []string{"upstream"}, Run: pass},
+			},
+			status: "not_ready",
+			want:   map[string]string{"upstream": "fail", "route": "skipped"},
+		},
+		{
+			name: "timeout fails the check",
+			checks: []Check{
+				{Name: "slow", Critical: true, Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+					<-ctx.Done()
+					return nil
+				}},
+			},
+			status: "not_ready",
+			want:   map[string]string{"slow": "fail"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			h := New("test", reg)
+			for _, c := range tt.checks {
+				h.Register(c)
+			}
+
+			report := h.Run(context.Background())
+			if report.Status != tt.status {
+				t.Errorf("status %q, want %q (%+v)", report.Status, tt.status, report.Checks)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("%d results, want %d", len(report.Checks), len(tt.checks))
+			}
+			for i, res := range report.Checks {
+				if res.Name != tt.checks[i].Name {
+					t.Errorf("result %d is %q, want registration order", i, res.Name)
+				}
+				if res.Status != tt.want[res.Name] {
+					t.Errorf("%s: status %q, want %q (error %q)", res.Name, res.Status, tt.want[res.Name], res.Error)
+				}
+				if (res.Status == "ok") != (res.Error == "") {
+					t.Errorf("%s: status %q with error %q", res.Name, res.Status, res.Error)
+				}
+				if up := checkUp(t, reg, res.Name); (up == 1) != (res.Status == "ok") {
+					t.Errorf("%s: health_check_up %v with status %q", res.Name, up, res.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestRunWaitsForDependencies(t *testing.T) {
+	h := New("test", prometheus.NewRegistry())
+	var dbDone bool
+	h.Register(Check{Name: "db", Run: func(context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		dbDone = true
+		return nil
+	}})
+	h.Register(Check{Name: "schema", DependsOn: []string{"db"}, Run: func(context.Context) error {
+		if !dbDone {
+			return errors.New("ran before db finished")
+		}
+		return nil
+	}})
+
+	if report := h.Run(context.Background()); report.Status != "ready" {
+		t.Errorf("status %q: %+v", report.Status, report.Checks)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+	}{
+		{name: "duplicate", checks: []Check{{Name: "db", Run: pass}, {Name: "db", Run: pass}}},
+		{name: "unknown dependency", checks: []Check{{Name: "schema", DependsOn: []string{"db"}, Run: pass}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Register did not panic")
+				}
+			}()
+			h := New("test", prometheus.NewRegistry())
+			for _, c := range tt.checks {
+				h.Register(c)
+			}
+		})
+	}
+}
+
+func TestNewSharesMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	a, b := New("a", reg), New("b", reg)
+	if a.up != b.up || a.duration != b.duration {
+		t.Error("second New on the same registry registered new collectors")
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name   string
+		check  Check
+		code   int
+		status string
+	}{
+		{name: "ready", check: Check{Name: "db", Critical: true, Run: pass}, code: http.StatusOK, status: "ready"},
+		{name: "degraded", check: Check{Name: "cache", Run: fail}, code: http.StatusOK, status: "degraded"},
+		{name: "not ready", check: Check{Name: "db", Critical: true, Run: fail}, code: http.StatusServiceUnavailable, status: "not_ready"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New("test", prometheus.NewRegistry())
+			h.Register(tt.check)
+
+			rec := httptest.NewRecorder()
+			h.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.code {
+				t.Errorf("code %d, want %d", rec.Code, tt.code)
+			}
+			var report Report
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Status != tt.status || report.Service != "test" {
+				t.Errorf("report %+v", report)
+			}
+		})
+	}
+}
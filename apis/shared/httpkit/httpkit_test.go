@@ -0,0 +1,36 @@
+package httpkit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusRecorder(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := NewStatusRecorder(w)
+	if rec.Status != http.StatusOK {
+		t.Errorf("initial status %d, want 200", rec.Status)
+	}
+
+	rec.WriteHeader(http.StatusTeapot)
+	rec.Write([]byte("short"))
+	rec.Write([]byte(" and stout"))
+	if rec.Status != http.StatusTeapot || w.Code != http.StatusTeapot {
+		t.Errorf("status %d (underlying %d), want 418", rec.Status, w.Code)
+	}
+	if rec.Bytes != 15 || w.Body.Len() != 15 {
+		t.Errorf("bytes %d (underlying %d), want 15", rec.Bytes, w.Body.Len())
+	}
+
+	// ResponseController finds the recorder's Flusher through Unwrap.
+	if err := http.NewResponseController(rec).Flush(); err != nil {
+		t.Errorf("Flush: %v", err)
+	}
+	if !w.Flushed {
+		t.Error("Flush did not reach the underlying writer")
+	}
+	if _, _, err := rec.Hijack(); err == nil {
+		t.Error("Hijack succeeded on a writer that cannot hijack")
+	}
+}
@@ -0,0 +1,45 @@
+// Package logging is the leveled logger used by the dinky APIs. The level is
+// set once from configuration at startup.
+package logging
+
+import "log"
+
+const (
+	levelDebug = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var levels = map[string]int{
+	"debug": levelDebug,
+	"info":  levelInfo,
+	"warn":  levelWarn,
+	"error": levelError,
+}
+
+var level = levelInfo
+
+// Valid reports whether name is a level SetLevel accepts.
+func Valid(name string) bool {
+	_, ok := levels[name]
+	return ok
+}
+
+// SetLevel drops messages below the named level. Unknown names mean debug;
+// configuration validates the name with Valid first.
+func SetLevel(name string) {
+	level = levels[name]
+}
+
+func Debugf(format string, args ...interface{}) { logAt(levelDebug, "DEBUG", format, args...) }
+func Infof(format string, args ...interface{})  { logAt(levelInfo, "INFO", format, args...) }
+func Warnf(format string, args ...interface{})  { logAt(levelWarn, "WARN", format, args...) }
+func Errorf(format string, args ...interface{}) { logAt(levelError, "ERROR", format, args...) }
+
+func logAt(l int, prefix, format string, args ...interface{}) {
+	if l < level {
+		return
+	}
+	log.Printf(prefix+" "+format, args...)
+}
@@ -0,0 +1,44 @@
+// Package respond writes JSON responses and the error envelope shared by the
+// dinky APIs, so clients see one error format whichever service answers:
+//
+//	{"error": {"code": "validation_failed", "message": "...", "details": [...]}}
+//
+// Error codes are defined by each service.
+package respond
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Envelope is the JSON shape of every error response.
+type Envelope struct {
+	Error APIError `json:"error"`
+}
+
+type APIError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// JSON writes v as the response body with the given status.
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func Error(w http.ResponseWriter, status int, code, message string) {
+	ErrorDetails(w, status, code, message, nil)
+}
+
+func ErrorDetails(w http.ResponseWriter, status int, code, message string, details []FieldError) {
+	JSON(w, status, Envelope{Error: APIError{Code: code, Message: message, Details: details}})
+}
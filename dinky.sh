@@ -694,8 +694,8 @@ handle_deploy_examples() {
             if [ -n "$api_port" ]; then
                 local server_ip=$(grep "SERVER_IP=" "$SCRIPT_DIR/.env" 2>/dev/null | cut -d'=' -f2 || hostname -I | awk '{print $1}')
                 echo -e "  ${CYAN}API URL:${NC} http://$server_ip:$api_port"
-                echo -e "  ${CYAN}Health Check:${NC} http://$server_ip:$api_port/health"
-                echo -e "  ${CYAN}Hello Endpoint:${NC} http://$server_ip:$api_port/hello"
+                echo -e "  ${CYAN}Health Check:${NC} http://$server_ip:$api_port/readyz"
+                echo -e "  ${CYAN}Hello Endpoint:${NC} http://$server_ip:$api_port/api/v1/hello"
             fi
         else
             error "Failed to deploy example API"